@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+// runAPIKeys handles "course-api api-keys [list|create <name> <scopes>|revoke <id>]".
+// It is how the first admin key gets made, after that /admin/api-keys works.
+func runAPIKeys(ctx context.Context, db database.Interface, args []string) error {
+	command := "list"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "list":
+		keys, err := db.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, k := range keys {
+			state := "active"
+			if !k.Active(now) {
+				state = "inactive"
+			}
+			fmt.Printf("%s  %-14s %-20s %-22s %s\n", k.Id, k.Prefix+"...", k.Name, strings.Join(k.Scopes, ","), state)
+		}
+		return nil
+	case "create":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: api-keys create <name> <scope>[,<scope>...]")
+		}
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		params := models.CreateAPIKeyParams{Name: args[1], Scopes: strings.Split(args[2], ",")}
+		if err := params.Validate(now); err != nil {
+			return err
+		}
+		plaintext, hash, prefix, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		key := models.APIKey{
+			Id:        uuid.New().String(),
+			Name:      params.Name,
+			Prefix:    prefix,
+			Scopes:    params.Scopes,
+			CreatedAt: now,
+		}
+		if err := db.CreateAPIKey(ctx, key, hash); err != nil {
+			return err
+		}
+		fmt.Printf("created api key %s, it is not shown again:\n%s\n", key.Id, plaintext)
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: api-keys revoke <id>")
+		}
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid id %q", args[1])
+		}
+		_, err = db.RevokeAPIKey(ctx, id, time.Now().UTC().Truncate(time.Microsecond))
+		return err
+	default:
+		return fmt.Errorf("unknown api-keys command %q, expected list, create or revoke", command)
+	}
+}
@@ -0,0 +1,173 @@
+// Package auth validates the HS256/RS256 bearer tokens sent to the api and
+// carries the verified claims through the request context.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// roles, each one includes the ones before it
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Claims are the token fields the api cares about
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// HasRole reports whether any of the claimed roles is at least role
+func (c *Claims) HasRole(role string) bool {
+	want, ok := roleRank[role]
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(c.Roles, func(r string) bool {
+		return roleRank[r] >= want
+	})
+}
+
+// Options says which keys and claims a Verifier accepts. At least one of
+// HMACSecret, PublicKeyFile or JWKSFile is needed.
+type Options struct {
+	HMACSecret    string
+	PublicKeyFile string
+	JWKSFile      string
+	Issuer        string
+	Audience      string
+	Leeway        time.Duration
+}
+
+type Verifier struct {
+	keys   keySet
+	parser *jwt.Parser
+}
+
+func NewVerifier(opts Options) (*Verifier, error) {
+	keys := keySet{}
+	if opts.HMACSecret != "" {
+		keys.hmac = append(keys.hmac, key{secret: []byte(opts.HMACSecret)})
+	}
+	if opts.PublicKeyFile != "" {
+		pem, err := os.ReadFile(opts.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", opts.PublicKeyFile, err)
+		}
+		keys.rsa = append(keys.rsa, key{public: pub})
+	}
+	if opts.JWKSFile != "" {
+		jwks, err := loadJWKS(opts.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", opts.JWKSFile, err)
+		}
+		keys.hmac = append(keys.hmac, jwks.hmac...)
+		keys.rsa = append(keys.rsa, jwks.rsa...)
+	}
+	if len(keys.hmac) == 0 && len(keys.rsa) == 0 {
+		return nil, errors.New("auth: no verification keys configured")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	return &Verifier{keys: keys, parser: jwt.NewParser(parserOpts...)}, nil
+}
+
+// Verify checks the signature and the registered claims of a raw token
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, v.keys.lookup)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims of the authenticated caller, if any
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+type key struct {
+	id     string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// keySet keeps hmac and rsa keys apart so an HS256 token can never be
+// checked against an rsa public key used as an hmac secret
+type keySet struct {
+	hmac []key
+	rsa  []key
+}
+
+func (ks keySet) lookup(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	var candidates []key
+	switch token.Method.Alg() {
+	case "HS256":
+		candidates = ks.hmac
+	case "RS256":
+		candidates = ks.rsa
+	}
+	// without a kid every key of the right type is tried
+	var matches jwt.VerificationKeySet
+	for _, k := range candidates {
+		if kid != "" && k.id != "" && k.id != kid {
+			continue
+		}
+		if k.public != nil {
+			matches.Keys = append(matches.Keys, k.public)
+		} else {
+			matches.Keys = append(matches.Keys, k.secret)
+		}
+	}
+	if len(matches.Keys) == 0 {
+		return nil, fmt.Errorf("no %s key for kid %q", token.Method.Alg(), kid)
+	}
+	return matches, nil
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func claims(roles ...string) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: roles,
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, c Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func publicKeyPEM(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v, err := NewVerifier(Options{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(RoleEditor)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Subject != "user-1" || !got.HasRole(RoleReader) || got.HasRole(RoleAdmin) {
+		t.Errorf("got claims %+v", got)
+	}
+
+	if _, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte("other"), "", claims())); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong secret: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := v.Verify(""); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("empty token: got %v, want ErrMissingToken", err)
+	}
+}
+
+func TestVerifyRegisteredClaims(t *testing.T) {
+	v, err := NewVerifier(Options{HMACSecret: testSecret, Issuer: "issuer", Audience: "course-api", Leeway: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := func() Claims {
+		c := claims(RoleReader)
+		c.Issuer = "issuer"
+		c.Audience = jwt.ClaimStrings{"course-api"}
+		return c
+	}
+	tests := []struct {
+		name  string
+		edit  func(c *Claims)
+		valid bool
+	}{
+		{"valid", func(c *Claims) {}, true},
+		{"expired within leeway", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-30 * time.Second)) }, true},
+		{"expired", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Minute)) }, false},
+		{"no exp", func(c *Claims) { c.ExpiresAt = nil }, false},
+		{"not yet valid", func(c *Claims) { c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour)) }, false},
+		{"wrong issuer", func(c *Claims) { c.Issuer = "someone-else" }, false},
+		{"no issuer", func(c *Claims) { c.Issuer = "" }, false},
+		{"wrong audience", func(c *Claims) { c.Audience = jwt.ClaimStrings{"other-api"} }, false},
+		{"no audience", func(c *Claims) { c.Audience = nil }, false},
+	}
+	for _, tt := range tests {
+		c := valid()
+		tt.edit(&c)
+		_, err := v.Verify(sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", c))
+		if tt.valid && err != nil {
+			t.Errorf("%s: got %v, want a valid token", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: got %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
+
+// an HS256 token signed with the rsa public key as the hmac secret must not
+// pass, nor may an unsigned one
+func TestVerifyRejectsAlgorithmConfusion(t *testing.T) {
+	key := rsaKey(t)
+	pubPEM := publicKeyPEM(t, key)
+	v, err := NewVerifier(Options{PublicKeyFile: writeFile(t, "pub.pem", pubPEM)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS256, key, "", claims(RoleReader))); err != nil {
+		t.Fatalf("RS256 token: %v", err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodHS256, pubPEM, "", claims(RoleAdmin))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("HS256 token signed with the public key: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(RoleAdmin))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unsigned token: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := v.Verify(sign(t, jwt.SigningMethodRS512, key, "", claims(RoleAdmin))); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("RS512 token: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyJWKSKidSelection(t *testing.T) {
+	keyA, keyB := rsaKey(t), rsaKey(t)
+	rsaJWK := func(kid string, key *rsa.PrivateKey) jwk {
+		return jwk{
+			Kty: "RSA", Kid: kid, Alg: "RS256", Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+	doc, err := json.Marshal(map[string][]jwk{"keys": {
+		rsaJWK("a", keyA),
+		rsaJWK("b", keyB),
+		{Kty: "oct", Kid: "h", K: base64.RawURLEncoding.EncodeToString([]byte(testSecret))},
+		// encryption keys are skipped
+		{Kty: "oct", Kid: "enc", Use: "enc", K: base64.RawURLEncoding.EncodeToString([]byte("enc-secret"))},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(Options{JWKSFile: writeFile(t, "jwks.json", doc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"kid a", sign(t, jwt.SigningMethodRS256, keyA, "a", claims()), true},
+		{"kid b", sign(t, jwt.SigningMethodRS256, keyB, "b", claims()), true},
+		{"no kid tries every rsa key", sign(t, jwt.SigningMethodRS256, keyB, "", claims()), true},
+		{"kid of another key", sign(t, jwt.SigningMethodRS256, keyB, "a", claims()), false},
+		{"unknown kid", sign(t, jwt.SigningMethodRS256, keyA, "c", claims()), false},
+		{"hmac kid", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "h", claims()), true},
+		{"rsa kid with hmac alg", sign(t, jwt.SigningMethodHS256, []byte(testSecret), "a", claims()), false},
+		{"encryption key", sign(t, jwt.SigningMethodHS256, []byte("enc-secret"), "enc", claims()), false},
+	}
+	for _, tt := range tests {
+		_, err := v.Verify(tt.token)
+		if tt.valid && err != nil {
+			t.Errorf("%s: got %v, want a valid token", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: got %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
+
+func TestNewVerifierNeedsKeys(t *testing.T) {
+	if _, err := NewVerifier(Options{}); err == nil {
+		t.Error("a verifier without keys should not be created")
+	}
+	if _, err := NewVerifier(Options{JWKSFile: writeFile(t, "jwks.json", []byte(`{"keys": []}`))}); err == nil {
+		t.Error("an empty JWKS should be rejected")
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		roles []string
+		role  string
+		want  bool
+	}{
+		{[]string{RoleReader}, RoleReader, true},
+		{[]string{RoleReader}, RoleEditor, false},
+		{[]string{RoleEditor}, RoleReader, true},
+		{[]string{RoleAdmin}, RoleEditor, true},
+		{[]string{"superuser"}, RoleReader, false},
+		{nil, RoleReader, false},
+		{[]string{RoleAdmin}, "superuser", false},
+	}
+	for _, tt := range tests {
+		c := Claims{Roles: tt.roles}
+		if got := c.HasRole(tt.role); got != tt.want {
+			t.Errorf("%v HasRole(%s) = %v, want %v", tt.roles, tt.role, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwk is the subset of RFC 7517 needed for RS256 ("RSA") and HS256 ("oct") keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// loadJWKS reads a local JWKS file. Keys for other algorithms or meant for
+// encryption are skipped.
+func loadJWKS(path string) (keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keySet{}, err
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return keySet{}, err
+	}
+	var ks keySet
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA" && (k.Alg == "" || k.Alg == "RS256"):
+			pub, err := k.rsaPublicKey()
+			if err != nil {
+				return keySet{}, fmt.Errorf("key %d: %w", i, err)
+			}
+			ks.rsa = append(ks.rsa, key{id: k.Kid, public: pub})
+		case k.Kty == "oct" && (k.Alg == "" || k.Alg == "HS256"):
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return keySet{}, fmt.Errorf("key %d: bad k", i)
+			}
+			ks.hmac = append(ks.hmac, key{id: k.Kid, secret: secret})
+		}
+	}
+	if len(ks.hmac) == 0 && len(ks.rsa) == 0 {
+		return keySet{}, errors.New("no usable RS256 or HS256 keys")
+	}
+	return ks, nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("bad modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("bad exponent")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
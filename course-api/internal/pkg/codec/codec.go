@@ -0,0 +1,279 @@
+// Package codec reads and writes api bodies as json, xml, yaml or
+// msgpack. The other formats are rendered from the json encoding of a value,
+// so field names, omitempty and custom marshalers are the same in all four.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Codec is one body format
+type Codec struct {
+	// MediaType is sent as Content-Type
+	MediaType string
+	// ProblemType is the Content-Type of RFC 7807 problem documents
+	ProblemType string
+	// other names clients use for the format
+	aliases []string
+	// encode writes doc, the parsed json of a value, under the root element
+	// name where the format has one
+	encode func(w io.Writer, root string, doc any) error
+	// decode reads the body into v, which the json package must accept
+	decode func(r io.Reader, v any) error
+}
+
+var (
+	JSON = &Codec{
+		MediaType:   "application/json",
+		ProblemType: "application/problem+json",
+	}
+	XML = &Codec{
+		MediaType:   "application/xml",
+		ProblemType: "application/problem+xml",
+		aliases:     []string{"text/xml"},
+		encode:      encodeXML,
+		decode:      decodeXML,
+	}
+	YAML = &Codec{
+		MediaType:   "application/yaml",
+		ProblemType: "application/yaml",
+		aliases:     []string{"application/x-yaml", "text/yaml"},
+		encode:      encodeYAML,
+		decode:      decodeYAML,
+	}
+	MsgPack = &Codec{
+		MediaType:   "application/msgpack",
+		ProblemType: "application/msgpack",
+		aliases:     []string{"application/x-msgpack", "application/vnd.msgpack"},
+		encode:      encodeMsgPack,
+		decode:      decodeMsgPack,
+	}
+)
+
+// All codecs, the first is the default
+var All = []*Codec{JSON, XML, YAML, MsgPack}
+
+// MediaTypes lists the main media type of every codec, for error messages
+func MediaTypes() string {
+	types := make([]string, len(All))
+	for i, c := range All {
+		types[i] = c.MediaType
+	}
+	return strings.Join(types, ", ")
+}
+
+// Name is the short name of the format, the subtype of MediaType: json, xml,
+// yaml or msgpack
+func (c *Codec) Name() string {
+	_, name, _ := strings.Cut(c.MediaType, "/")
+	return name
+}
+
+// Negotiate picks the codec the Accept header prefers, by q value and then in
+// the order of All. A missing header gets JSON. nil means the header rules
+// out every codec.
+func Negotiate(accept string) *Codec {
+	if strings.TrimSpace(accept) == "" {
+		return JSON
+	}
+	var best *Codec
+	var bestQ float64
+	for _, c := range All {
+		if q := c.quality(accept); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+// ForContentType returns the codec for a request Content-Type, JSON when it
+// is empty and nil when no codec reads it
+func ForContentType(contentType string) *Codec {
+	if contentType == "" {
+		return JSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, c := range All {
+		if c.is(mediaType) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *Codec) is(mediaType string) bool {
+	if mediaType == c.MediaType {
+		return true
+	}
+	for _, alias := range c.aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// quality is the q value accept gives the codec, taken from the most specific
+// media range matching any of its names
+func (c *Codec) quality(accept string) float64 {
+	var best float64
+	for _, name := range append([]string{c.MediaType}, c.aliases...) {
+		q, specificity := 0.0, 0
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			s := matches(mediaRange, name)
+			if s <= specificity {
+				continue
+			}
+			specificity, q = s, 1
+			if val, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(val, 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		best = max(best, q)
+	}
+	return best
+}
+
+// matches says how specifically mediaRange covers mediaType: 3 exactly, 2 as
+// type/*, 1 as */* and 0 not at all
+func matches(mediaRange, mediaType string) int {
+	if mediaRange == mediaType {
+		return 3
+	}
+	if mediaRange == "*/*" {
+		return 1
+	}
+	kind, _, _ := strings.Cut(mediaType, "/")
+	if mediaRange == kind+"/*" {
+		return 2
+	}
+	return 0
+}
+
+// Marshal encodes v in the codec's format. Formats with a root element name
+// it after v's type, e.g. course_list for models.CourseList.
+func (c *Codec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	if c.encode == nil {
+		return buf.Bytes(), nil
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	doc, err := parse(dec)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := c.encode(&out, rootName(reflect.TypeOf(v)), doc); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decode reads one document from r into v. An empty body gives io.EOF.
+func (c *Codec) Decode(r io.Reader, v any) error {
+	if c.decode == nil {
+		return json.NewDecoder(r).Decode(v)
+	}
+	return c.decode(r, v)
+}
+
+// object is a json object with its keys in order
+type object []member
+
+type member struct {
+	key   string
+	value any
+}
+
+// parse reads the next json value as an object, []any, string, json.Number,
+// bool or nil
+func parse(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parse(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: key.(string), value: value})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			value, err := parse(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// viaJSON stores a generic decoded document into v through its json
+// encoding, so v decodes the same way from every format
+func viaJSON(doc, v any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// rootName snake cases the name of t, a slice of T gives Ts. Unnamed types
+// get "response".
+func rootName(t reflect.Type) string {
+	suffix := ""
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		if t.Kind() == reflect.Slice {
+			suffix = "s"
+		}
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return "response"
+	}
+	name := []rune(t.Name())
+	var out []rune
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) &&
+			(!unicode.IsUpper(name[i-1]) || i+1 < len(name) && unicode.IsLower(name[i+1])) {
+			out = append(out, '_')
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out) + suffix
+}
@@ -0,0 +1,105 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   *Codec
+	}{
+		{"", JSON},
+		{"*/*", JSON},
+		{"application/json", JSON},
+		{"application/xml", XML},
+		{"text/xml", XML},
+		{"application/x-yaml", YAML},
+		{"application/vnd.msgpack", MsgPack},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", XML},
+		{"application/json;q=0.5, application/msgpack", MsgPack},
+		// a specific range beats a wildcard for the same codec
+		{"*/*;q=0.9, application/json;q=0", XML},
+		{"application/*", JSON},
+		{"image/png", nil},
+		{"application/json;q=0", nil},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.accept); got != tt.want {
+			t.Errorf("Negotiate(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        *Codec
+	}{
+		{"", JSON},
+		{"application/json; charset=utf-8", JSON},
+		{"text/xml", XML},
+		{"application/yaml", YAML},
+		{"application/x-msgpack", MsgPack},
+		{"text/plain", nil},
+		{"application/problem+json", nil},
+		{"not a media type;", nil},
+	}
+	for _, tt := range tests {
+		if got := ForContentType(tt.contentType); got != tt.want {
+			t.Errorf("ForContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	deleted := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	values := []any{
+		&models.Course{Id: "a", Name: "Go & <XML>", Price: 10.5, Technology: []string{"Go", "gRPC"}, Version: 3, DeletedAt: &deleted},
+		&models.Course{Id: "b", Name: "Empty", Technology: []string{}},
+		&models.CourseList{Data: []models.Course{{Id: "a", Name: "Go", Price: 1, Technology: []string{"Go"}, Version: 1}}},
+	}
+	for _, c := range All {
+		for _, v := range values {
+			out, err := c.Marshal(v)
+			if err != nil {
+				t.Fatalf("%s: marshal %+v: %v", c.Name(), v, err)
+			}
+			got := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+			if err := c.Decode(bytes.NewReader(out), got); err != nil {
+				t.Fatalf("%s: decode %s: %v", c.Name(), out, err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("%s: round trip of %+v gave %+v", c.Name(), v, got)
+			}
+		}
+	}
+}
+
+// xml documents are named after the type
+func TestXMLRootName(t *testing.T) {
+	out, err := XML.Marshal(models.CourseList{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "<course_list>") {
+		t.Errorf("xml %s, want a course_list root element", out)
+	}
+}
+
+func TestDecodeEmptyBody(t *testing.T) {
+	for _, c := range All {
+		var course models.Course
+		if err := c.Decode(strings.NewReader(""), &course); !errors.Is(err, io.EOF) {
+			t.Errorf("%s: empty body gave %v, want io.EOF", c.Name(), err)
+		}
+	}
+}
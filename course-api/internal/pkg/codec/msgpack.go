@@ -0,0 +1,65 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func encodeMsgPack(w io.Writer, _ string, doc any) error {
+	return writeMsgPack(msgpack.NewEncoder(w), doc)
+}
+
+// writeMsgPack keeps the key order of the json and sends whole numbers as
+// integers
+func writeMsgPack(enc *msgpack.Encoder, doc any) error {
+	switch v := doc.(type) {
+	case object:
+		if err := enc.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for _, m := range v {
+			if err := enc.EncodeString(m.key); err != nil {
+				return err
+			}
+			if err := writeMsgPack(enc, m.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := enc.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := writeMsgPack(enc, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case string:
+		return enc.EncodeString(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return enc.EncodeInt(i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.EncodeFloat64(f)
+	case bool:
+		return enc.EncodeBool(v)
+	default:
+		return enc.EncodeNil()
+	}
+}
+
+func decodeMsgPack(r io.Reader, v any) error {
+	var doc any
+	if err := msgpack.NewDecoder(r).Decode(&doc); err != nil {
+		return err
+	}
+	return viaJSON(doc, v)
+}
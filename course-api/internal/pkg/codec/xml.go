@@ -0,0 +1,205 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Documents are plain elements named after the json keys. Array items are
+// <item> elements, so empty and single item arrays read back as arrays:
+//
+//	<course><name>Go</name><technology><item>go</item></technology></course>
+func encodeXML(w io.Writer, root string, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return writeXML(w, root, doc)
+}
+
+func writeXML(w io.Writer, name string, doc any) error {
+	if _, err := fmt.Fprintf(w, "<%s>", name); err != nil {
+		return err
+	}
+	switch v := doc.(type) {
+	case object:
+		for _, m := range v {
+			if err := writeXML(w, m.key, m.value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXML(w, "item", item); err != nil {
+				return err
+			}
+		}
+	case string:
+		if err := xml.EscapeText(w, []byte(v)); err != nil {
+			return err
+		}
+	case json.Number:
+		io.WriteString(w, v.String())
+	case bool:
+		io.WriteString(w, strconv.FormatBool(v))
+	}
+	_, err := fmt.Fprintf(w, "</%s>", name)
+	return err
+}
+
+// element is a parsed xml element, text is only kept for leaves
+type element struct {
+	name     string
+	text     string
+	children []*element
+}
+
+// decodeXML reads the document the way encodeXML writes it. Leaves carry no
+// type, so v's type decides whether each one is a string, number or bool.
+func decodeXML(r io.Reader, v any) error {
+	root, err := readElement(xml.NewDecoder(r), nil)
+	if err != nil {
+		return err
+	}
+	doc, err := xmlValue(root, reflect.TypeOf(v))
+	if err != nil {
+		return err
+	}
+	return viaJSON(doc, v)
+}
+
+// readElement reads up to the end of start, or the first element when start
+// is nil
+func readElement(dec *xml.Decoder, start *xml.StartElement) (*element, error) {
+	var el *element
+	if start != nil {
+		el = &element{name: start.Name.Local}
+	}
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) && el != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := readElement(dec, &tok)
+			if err != nil {
+				return nil, err
+			}
+			if el == nil {
+				return child, nil
+			}
+			el.children = append(el.children, child)
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			if len(el.children) == 0 {
+				el.text = text.String()
+			}
+			return el, nil
+		}
+	}
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// xmlValue turns el into the json value t decodes from
+func xmlValue(el *element, t reflect.Type) (any, error) {
+	if t.Kind() == reflect.Pointer {
+		return xmlValue(el, t.Elem())
+	}
+	// e.g. time.Time, which json reads from a string
+	if ptr := reflect.PointerTo(t); ptr.Implements(jsonUnmarshaler) || ptr.Implements(textUnmarshaler) {
+		return strings.TrimSpace(el.text), nil
+	}
+	text := strings.TrimSpace(el.text)
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		obj := map[string]any{}
+		for _, child := range el.children {
+			fieldType, ok := fields[child.name]
+			if !ok {
+				continue
+			}
+			value, err := xmlValue(child, fieldType)
+			if err != nil {
+				return nil, err
+			}
+			obj[child.name] = value
+		}
+		return obj, nil
+	case reflect.Map:
+		obj := map[string]any{}
+		for _, child := range el.children {
+			value, err := xmlValue(child, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			obj[child.name] = value
+		}
+		return obj, nil
+	case reflect.Slice, reflect.Array:
+		arr := []any{}
+		for _, child := range el.children {
+			value, err := xmlValue(child, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		return arr, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("xml: <%s> must be true or false", el.name)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, fmt.Errorf("xml: <%s> must be a number", el.name)
+		}
+		return json.Number(text), nil
+	default:
+		return el.text, nil
+	}
+}
+
+// jsonFields maps the json names of t's fields to their types, with embedded
+// structs flattened the way encoding/json does
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for name, fieldType := range jsonFields(f.Type) {
+				fields[name] = fieldType
+			}
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,228 @@
+// Package config loads the course-api settings. Every field can come from
+// (lowest to highest precedence) the defaults below, a yaml or toml file,
+// an environment variable and a command line flag; the env and flag names are
+// in the struct tags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	Features  FeatureConfig   `yaml:"features" toml:"features"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" toml:"addr" env:"LISTEN_ADDR" flag:"addr" usage:"address the http server listens on"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"time allowed to read request headers"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT" flag:"read-timeout" usage:"time allowed to read a whole request"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT" flag:"write-timeout" usage:"time allowed to write a response"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT" flag:"idle-timeout" usage:"how long keep-alive connections stay open"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"how long in-flight requests get to finish on shutdown"`
+	BulkMaxBatchSize  int           `yaml:"bulk_max_batch_size" toml:"bulk_max_batch_size" env:"BULK_MAX_BATCH_SIZE" flag:"bulk-max-batch-size" usage:"most courses accepted by one POST /courses/bulk"`
+	ImportMaxRows     int           `yaml:"import_max_rows" toml:"import_max_rows" env:"IMPORT_MAX_ROWS" flag:"import-max-rows" usage:"most csv rows accepted by one POST /courses/import"`
+}
+
+type DatabaseConfig struct {
+	URL             string        `yaml:"url" toml:"url" env:"DATABASE_URL" flag:"db-url" usage:"mysql dsn, sqlite:///path or memory://" secret:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open database connections"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle database connections"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a database connection"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" usage:"maximum idle time of a database connection"`
+	MigrateOnStart  bool          `yaml:"migrate_on_start" toml:"migrate_on_start" env:"MIGRATE_ON_START" flag:"migrate" usage:"apply pending schema migrations before starting the server"`
+	// startup retries the first connection with backoff up to this wait
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff" toml:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF" flag:"db-connect-max-backoff" usage:"longest wait between database connection attempts on startup"`
+	HealthTimeout     time.Duration `yaml:"health_timeout" toml:"health_timeout" env:"DB_HEALTH_TIMEOUT" flag:"db-health-timeout" usage:"timeout of the database ping in /readyz"`
+}
+
+// AuthConfig controls the checks on the course and admin routes. Api keys are
+// always accepted when Enabled, bearer tokens only when HMACSecret,
+// PublicKeyFile or JWKSFile is set. Enabled is on by default, an open api has
+// to be asked for with enabled=false.
+type AuthConfig struct {
+	Enabled       bool          `yaml:"enabled" toml:"enabled" env:"AUTH_ENABLED" flag:"auth" usage:"require a bearer token or api key on the course routes"`
+	HMACSecret    string        `yaml:"hmac_secret" toml:"hmac_secret" env:"AUTH_HMAC_SECRET" usage:"shared secret for HS256 tokens" secret:"true"`
+	PublicKeyFile string        `yaml:"public_key_file" toml:"public_key_file" env:"AUTH_PUBLIC_KEY_FILE" flag:"auth-public-key" usage:"PEM rsa public key for RS256 tokens"`
+	JWKSFile      string        `yaml:"jwks_file" toml:"jwks_file" env:"AUTH_JWKS_FILE" flag:"auth-jwks" usage:"local JWKS file with RS256 and/or HS256 keys"`
+	Issuer        string        `yaml:"issuer" toml:"issuer" env:"AUTH_ISSUER" flag:"auth-issuer" usage:"required iss claim, empty to skip the check"`
+	Audience      string        `yaml:"audience" toml:"audience" env:"AUTH_AUDIENCE" flag:"auth-audience" usage:"required aud claim, empty to skip the check"`
+	Leeway        time.Duration `yaml:"leeway" toml:"leeway" env:"AUTH_LEEWAY" flag:"auth-leeway" usage:"clock skew allowed on exp and nbf"`
+}
+
+// HasJWTKeys reports whether bearer tokens can be verified
+func (a AuthConfig) HasJWTKeys() bool {
+	return a.HMACSecret != "" || a.PublicKeyFile != "" || a.JWKSFile != ""
+}
+
+// RateLimitConfig sets the token buckets per client. Reads (GET, HEAD) and
+// writes count against separate buckets so writes can be held tighter.
+type RateLimitConfig struct {
+	Enabled           bool          `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED" flag:"rate-limit" usage:"limit requests per client"`
+	ReadsPerMinute    int           `yaml:"reads_per_minute" toml:"reads_per_minute" env:"RATE_LIMIT_READS_PER_MINUTE" flag:"rate-limit-reads" usage:"sustained GET/HEAD requests per client per minute"`
+	ReadBurst         int           `yaml:"read_burst" toml:"read_burst" env:"RATE_LIMIT_READ_BURST" flag:"rate-limit-read-burst" usage:"GET/HEAD requests a client can make back to back"`
+	WritesPerMinute   int           `yaml:"writes_per_minute" toml:"writes_per_minute" env:"RATE_LIMIT_WRITES_PER_MINUTE" flag:"rate-limit-writes" usage:"sustained write requests per client per minute"`
+	WriteBurst        int           `yaml:"write_burst" toml:"write_burst" env:"RATE_LIMIT_WRITE_BURST" flag:"rate-limit-write-burst" usage:"write requests a client can make back to back"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"RATE_LIMIT_IDLE_TIMEOUT" flag:"rate-limit-idle-timeout" usage:"forget clients idle for this long"`
+	TrustForwardedFor bool          `yaml:"trust_forwarded_for" toml:"trust_forwarded_for" env:"RATE_LIMIT_TRUST_FORWARDED_FOR" flag:"rate-limit-trust-forwarded-for" usage:"key anonymous clients by X-Forwarded-For, only behind a proxy that sets it"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"text or json"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"METRICS_ENABLED" flag:"metrics" usage:"serve prometheus metrics on /metrics"`
+}
+
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" flag:"tracing" usage:"none, stdout or otlp"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" flag:"tracing-otlp-endpoint" usage:"otlp http endpoint, e.g. http://localhost:4318 (default from OTEL_EXPORTER_OTLP_ENDPOINT)"`
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME" flag:"tracing-service-name" usage:"service.name reported with every span"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" usage:"fraction of new traces to record, 0 to 1"`
+}
+
+type FeatureConfig struct {
+	Patch bool `yaml:"patch" toml:"patch" env:"FEATURE_PATCH" flag:"feature-patch" usage:"serve PATCH /courses/{id}"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":6060",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   15 * time.Second,
+			BulkMaxBatchSize:  500,
+			ImportMaxRows:     10000,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:      25,
+			MaxIdleConns:      25,
+			ConnMaxLifetime:   5 * time.Minute,
+			ConnMaxIdleTime:   time.Minute,
+			ConnectMaxBackoff: 30 * time.Second,
+			HealthTimeout:     2 * time.Second,
+		},
+		Auth: AuthConfig{
+			Enabled: true,
+			Leeway:  30 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:         true,
+			ReadsPerMinute:  600,
+			ReadBurst:       100,
+			WritesPerMinute: 60,
+			WriteBurst:      20,
+			IdleTimeout:     10 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "course-api",
+			SampleRatio: 1,
+		},
+		Features: FeatureConfig{
+			Patch: true,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr: %w", err))
+	}
+	for name, d := range map[string]time.Duration{
+		"server.read_header_timeout":  c.Server.ReadHeaderTimeout,
+		"server.read_timeout":         c.Server.ReadTimeout,
+		"server.write_timeout":        c.Server.WriteTimeout,
+		"server.idle_timeout":         c.Server.IdleTimeout,
+		"server.shutdown_timeout":     c.Server.ShutdownTimeout,
+		"database.conn_max_lifetime":  c.Database.ConnMaxLifetime,
+		"database.conn_max_idle_time": c.Database.ConnMaxIdleTime,
+		"auth.leeway":                 c.Auth.Leeway,
+		"rate_limit.idle_timeout":     c.RateLimit.IdleTimeout,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s cannot be negative", name))
+		}
+	}
+	if c.Server.BulkMaxBatchSize <= 0 {
+		errs = append(errs, errors.New("server.bulk_max_batch_size must be greater than 0"))
+	}
+	if c.Server.ImportMaxRows <= 0 {
+		errs = append(errs, errors.New("server.import_max_rows must be greater than 0"))
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database.url is required (DATABASE_URL)"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database connection limits cannot be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns cannot exceed database.max_open_conns"))
+	}
+	if c.Database.ConnectMaxBackoff <= 0 {
+		errs = append(errs, errors.New("database.connect_max_backoff must be greater than 0"))
+	}
+	if c.Database.HealthTimeout <= 0 {
+		errs = append(errs, errors.New("database.health_timeout must be greater than 0"))
+	}
+	// api keys are created with the api-keys subcommand, which needs a
+	// database that outlives the process
+	if c.Auth.Enabled && !c.Auth.HasJWTKeys() && strings.HasPrefix(c.Database.URL, "memory://") {
+		errs = append(errs, errors.New("auth.enabled needs a jwt key (AUTH_HMAC_SECRET, AUTH_PUBLIC_KEY_FILE or AUTH_JWKS_FILE) with the memory backend, or set AUTH_ENABLED=false"))
+	}
+	if c.RateLimit.Enabled {
+		for name, n := range map[string]int{
+			"rate_limit.reads_per_minute":  c.RateLimit.ReadsPerMinute,
+			"rate_limit.read_burst":        c.RateLimit.ReadBurst,
+			"rate_limit.writes_per_minute": c.RateLimit.WritesPerMinute,
+			"rate_limit.write_burst":       c.RateLimit.WriteBurst,
+		} {
+			if n <= 0 {
+				errs = append(errs, fmt.Errorf("%s must be greater than 0", name))
+			}
+		}
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q must be none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q must be debug, info, warn or error", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q must be text or json", c.Log.Format))
+	}
+	return errors.Join(errs...)
+}
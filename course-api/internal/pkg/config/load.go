@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "*****"
+
+// field is one leaf setting of Config together with its struct tags
+type field struct {
+	path   string
+	env    string
+	flag   string
+	usage  string
+	secret string
+	value  reflect.Value
+}
+
+// fields walks cfg and returns every leaf field, addressable so it can be set
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			out = append(out, fields(fv, name)...)
+			continue
+		}
+		out = append(out, field{
+			path:   name,
+			env:    sf.Tag.Get("env"),
+			flag:   sf.Tag.Get("flag"),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret"),
+			value:  fv,
+		})
+	}
+	return out
+}
+
+// setValue parses s into v based on its type
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// flagValue records a flag so it can be applied after the file and the
+// environment, which gives flags the highest precedence
+type flagValue struct {
+	field   *field
+	pending *[]func()
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.field == nil {
+		return ""
+	}
+	return fmt.Sprint(f.field.value.Interface())
+}
+
+func (f *flagValue) Set(s string) error {
+	// parse into a scratch value first so bad input fails flag parsing
+	scratch := reflect.New(f.field.value.Type()).Elem()
+	if err := setValue(scratch, s); err != nil {
+		return err
+	}
+	target := f.field.value
+	*f.pending = append(*f.pending, func() {
+		target.Set(scratch)
+	})
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.field.value.Kind() == reflect.Bool
+}
+
+// Load registers -config and one flag per setting on fs, parses args and
+// layers defaults, the config file (-config or CONFIG_FILE), environment
+// variables and flags. The result is validated.
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+	all := fields(reflect.ValueOf(&cfg).Elem(), "")
+
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "yaml or toml config file (env CONFIG_FILE)")
+	var pending []func()
+	for i := range all {
+		f := &all[i]
+		if f.flag == "" {
+			continue
+		}
+		usage := f.usage
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		fs.Var(&flagValue{field: f, pending: &pending}, f.flag, usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+	for _, f := range all {
+		if f.env == "" {
+			continue
+		}
+		if val, ok := os.LookupEnv(f.env); ok && val != "" {
+			if err := setValue(f.value, val); err != nil {
+				return cfg, fmt.Errorf("%s (%s): %w", f.env, f.path, err)
+			}
+		}
+	}
+	for _, apply := range pending {
+		apply()
+	}
+	return cfg, cfg.Validate()
+}
+
+// loadFile decodes a yaml or toml file over cfg, rejecting unknown keys
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("%s: config file must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Redacted returns a copy that is safe to log
+func (c Config) Redacted() Config {
+	for _, f := range fields(reflect.ValueOf(&c).Elem(), "") {
+		if f.secret == "" || f.value.Kind() != reflect.String || f.value.String() == "" {
+			continue
+		}
+		if f.secret == "dsn" {
+			f.value.SetString(redactDSN(f.value.String()))
+		} else {
+			f.value.SetString(redacted)
+		}
+	}
+	return c
+}
+
+// redactDSN hides the password in user:password@host style urls
+func redactDSN(dsn string) string {
+	at := strings.LastIndex(dsn, "@")
+	if at < 0 {
+		return dsn
+	}
+	start := strings.Index(dsn, "://") + 3
+	if start < 3 {
+		start = 0
+	}
+	colon := strings.Index(dsn[start:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:start+colon+1] + redacted + dsn[at:]
+}
+
+// Print writes the redacted config as yaml
+func (c Config) Print(w io.Writer) error {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
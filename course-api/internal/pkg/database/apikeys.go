@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// sqlAPIKeys implements APIKeyStore for mysql and sqlite, the queries are the
+// same for both. It is embedded in CoursesDBSession and SQLiteSession.
+type sqlAPIKeys struct {
+	dbx *sqlx.DB
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+func (s sqlAPIKeys) CreateAPIKey(ctx context.Context, key models.APIKey, hash string) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return &Error{Op: "create api key", Kind: ErrInvalidData, Err: err}
+	}
+	query := `INSERT INTO api_keys (id, name, prefix, key_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = s.dbx.ExecContext(ctx, query, key.Id, key.Name, key.Prefix, hash, string(scopes), key.CreatedAt, key.ExpiresAt)
+	return translateError("create api key", err)
+}
+
+func (s sqlAPIKeys) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var rows []models.APIKeyDatabase
+	err := s.dbx.SelectContext(ctx, &rows, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, translateError("list api keys", err)
+	}
+	keys := make([]models.APIKey, 0, len(rows))
+	for _, row := range rows {
+		key, err := apiKeyFromRow(row)
+		if err != nil {
+			return nil, &Error{Op: "list api keys", Kind: ErrInvalidData, Err: err}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s sqlAPIKeys) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var row models.APIKeyDatabase
+	err := s.dbx.GetContext(ctx, &row, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, hash)
+	if err != nil {
+		return models.APIKey{}, translateError("get api key", err)
+	}
+	key, err := apiKeyFromRow(row)
+	if err != nil {
+		return models.APIKey{}, &Error{Op: "get api key", Kind: ErrInvalidData, Err: err}
+	}
+	return key, nil
+}
+
+func (s sqlAPIKeys) getAPIKey(ctx context.Context, op string, id uuid.UUID) (models.APIKey, error) {
+	var row models.APIKeyDatabase
+	err := s.dbx.GetContext(ctx, &row, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id.String())
+	if err != nil {
+		return models.APIKey{}, translateError(op, err)
+	}
+	key, err := apiKeyFromRow(row)
+	if err != nil {
+		return models.APIKey{}, &Error{Op: op, Kind: ErrInvalidData, Err: err}
+	}
+	return key, nil
+}
+
+func (s sqlAPIKeys) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error) {
+	query := `UPDATE api_keys SET key_hash = ?, prefix = ? WHERE id = ? AND revoked_at IS NULL`
+	result, err := s.dbx.ExecContext(ctx, query, hash, prefix, id.String())
+	if err != nil {
+		return models.APIKey{}, translateError("rotate api key", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.APIKey{}, apiKeyNotFound("rotate api key", id)
+	}
+	return s.getAPIKey(ctx, "rotate api key", id)
+}
+
+func (s sqlAPIKeys) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (models.APIKey, error) {
+	// revoking twice keeps the first revocation time
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`
+	result, err := s.dbx.ExecContext(ctx, query, at, id.String())
+	if err != nil {
+		return models.APIKey{}, translateError("revoke api key", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return models.APIKey{}, apiKeyNotFound("revoke api key", id)
+	}
+	return s.getAPIKey(ctx, "revoke api key", id)
+}
+
+func (s sqlAPIKeys) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := s.dbx.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, at, id.String())
+	return translateError("touch api key", err)
+}
+
+func apiKeyFromRow(row models.APIKeyDatabase) (models.APIKey, error) {
+	scopes, err := models.ConvertToSlice(row.Scopes)
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("api key %s: %w", row.Id, err)
+	}
+	return models.APIKey{
+		Id:         row.Id,
+		Name:       row.Name,
+		Prefix:     row.Prefix,
+		Scopes:     scopes,
+		CreatedAt:  row.CreatedAt.UTC(),
+		ExpiresAt:  utc(row.ExpiresAt),
+		LastUsedAt: utc(row.LastUsedAt),
+		RevokedAt:  utc(row.RevokedAt),
+	}, nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func apiKeyNotFound(op string, id uuid.UUID) error {
+	return &Error{Op: op, Kind: ErrNotFound, Err: fmt.Errorf("no api key with id %s", id)}
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// courses per multi-row INSERT, far below the bound parameter limits of mysql
+// (65535) and sqlite (32766)
+const insertBatchSize = 100
+
+// rows per statement in linkTechnologies, a batch of courses can carry any
+// number of technologies
+const technologyBatchSize = 1000
+
+// BulkResult is the outcome of one course passed to CreateMany, Err is nil
+// when it was created
+type BulkResult struct {
+	Course models.Course
+	Err    error
+}
+
+// BulkItemError fails an atomic CreateMany, Index is the position in params
+// of the course at fault
+type BulkItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BulkItemError) Error() string {
+	return fmt.Sprintf("course %d: %v", e.Index, e.Err)
+}
+
+func (e *BulkItemError) Unwrap() error {
+	return e.Err
+}
+
+// createMany implements CreateMany for both sql backends. Atomic runs every
+// batch in one transaction and fails as a whole, with a BulkItemError naming
+// the first course at fault when there is one. Otherwise each batch commits
+// on its own, and a batch that fails is retried one course at a time so only
+// the courses at fault are reported.
+func createMany(ctx context.Context, dbx *sqlx.DB, d dialect, params []models.CreateCourseParams, atomic bool) ([]BulkResult, error) {
+	courses := make([]models.Course, len(params))
+	for i, p := range params {
+		courses[i] = models.Course{
+			Id:         uuid.New().String(),
+			Name:       p.Name,
+			Price:      p.Price,
+			Technology: uniqueTechnologies(p.Technology),
+			Version:    1,
+		}
+	}
+	results := make([]BulkResult, len(courses))
+
+	if atomic {
+		err := withTx(ctx, dbx, func(tx *sqlx.Tx) error {
+			for batch := range slices.Chunk(courses, insertBatchSize) {
+				if err := insertCourses(ctx, tx, d, batch); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if index, rowErr := failingCourse(ctx, dbx, d, courses); rowErr != nil {
+				return nil, &BulkItemError{Index: index, Err: translateError("create course", rowErr)}
+			}
+			return nil, translateError("create courses", err)
+		}
+		for i, course := range courses {
+			results[i].Course = course
+		}
+		return results, nil
+	}
+
+	for start := 0; start < len(courses); start += insertBatchSize {
+		batch := courses[start:min(start+insertBatchSize, len(courses))]
+		err := withTx(ctx, dbx, func(tx *sqlx.Tx) error {
+			return insertCourses(ctx, tx, d, batch)
+		})
+		for i, course := range batch {
+			if err == nil {
+				results[start+i].Course = course
+				continue
+			}
+			rowErr := withTx(ctx, dbx, func(tx *sqlx.Tx) error {
+				return insertCourses(ctx, tx, d, []models.Course{course})
+			})
+			if rowErr != nil {
+				results[start+i].Err = translateError("create course", rowErr)
+			} else {
+				results[start+i].Course = course
+			}
+		}
+	}
+	return results, nil
+}
+
+// failingCourse replays a failed atomic create one course at a time in a
+// transaction that is rolled back, returning the first course that fails and
+// its error. The error is nil when every course goes in on its own, the
+// batch failed for some other reason.
+func failingCourse(ctx context.Context, dbx *sqlx.DB, d dialect, courses []models.Course) (int, error) {
+	tx, err := dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, nil
+	}
+	defer tx.Rollback()
+	for i, course := range courses {
+		if err := insertCourses(ctx, tx, d, []models.Course{course}); err != nil {
+			return i, err
+		}
+	}
+	return 0, nil
+}
+
+// importCourses implements Import for both sql backends. Courses are looked
+// up by id a batch at a time: known ones are updated, bumping their version
+// and restoring them if deleted, the others inserted.
+func importCourses(ctx context.Context, dbx *sqlx.DB, d dialect, courses []models.Course, dryRun bool) (models.ImportSummary, error) {
+	summary := models.ImportSummary{DryRun: dryRun, Rows: len(courses)}
+	for i := range courses {
+		if courses[i].Id == "" {
+			courses[i].Id = uuid.New().String()
+		}
+		courses[i].Technology = uniqueTechnologies(courses[i].Technology)
+	}
+	tx, err := dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.ImportSummary{}, translateError("import courses", err)
+	}
+	defer tx.Rollback()
+	for batch := range slices.Chunk(courses, insertBatchSize) {
+		updated, err := upsertCourses(ctx, tx, d, batch)
+		if err != nil {
+			return models.ImportSummary{}, translateError("import courses", err)
+		}
+		summary.Updated += updated
+		summary.Created += len(batch) - updated
+	}
+	if dryRun {
+		return summary, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ImportSummary{}, translateError("import courses", err)
+	}
+	return summary, nil
+}
+
+// upsertCourses updates the courses that exist and inserts the rest,
+// returning how many were updated
+func upsertCourses(ctx context.Context, tx *sqlx.Tx, d dialect, courses []models.Course) (int, error) {
+	ids := make([]string, len(courses))
+	for i, course := range courses {
+		ids[i] = course.Id
+	}
+	// deleted courses count as existing, replacing one restores it
+	query, args, err := sqlx.In(`SELECT id FROM courses WHERE id IN (?)`, ids)
+	if err != nil {
+		return 0, err
+	}
+	var existing []string
+	if err := tx.SelectContext(ctx, &existing, query, args...); err != nil {
+		return 0, err
+	}
+	var inserts []models.Course
+	for _, course := range courses {
+		if !slices.Contains(existing, course.Id) {
+			inserts = append(inserts, course)
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE courses SET name = ?, price = ?, version = version + 1, deleted_at = NULL WHERE id = ?`, course.Name, course.Price, course.Id); err != nil {
+			return 0, err
+		}
+	}
+	if len(existing) > 0 {
+		query, args, err := sqlx.In(`DELETE FROM course_technologies WHERE course_id IN (?)`, existing)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return 0, err
+		}
+	}
+	if len(inserts) > 0 {
+		args := make([]any, 0, 3*len(inserts))
+		for _, course := range inserts {
+			args = append(args, course.Id, course.Name, course.Price)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO courses (id, name, price) VALUES `+placeholders(len(inserts), 3), args...); err != nil {
+			return 0, err
+		}
+	}
+	return len(existing), linkTechnologies(ctx, tx, d, courses)
+}
+
+// insertCourses writes new courses and their technologies with multi-row
+// INSERTs
+func insertCourses(ctx context.Context, tx *sqlx.Tx, d dialect, courses []models.Course) error {
+	args := make([]any, 0, 3*len(courses))
+	for _, course := range courses {
+		args = append(args, course.Id, course.Name, course.Price)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO courses (id, name, price) VALUES `+placeholders(len(courses), 3), args...); err != nil {
+		return err
+	}
+	return linkTechnologies(ctx, tx, d, courses)
+}
+
+// withTx runs fn in a transaction, committing when it returns nil
+func withTx(ctx context.Context, dbx *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
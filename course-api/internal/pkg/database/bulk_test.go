@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+// rejectingSQLite is a sqlite store that refuses courses named "rejected"
+func rejectingSQLite(t *testing.T) *SQLiteSession {
+	t.Helper()
+	db := backends(t)["sqlite"].(*SQLiteSession)
+	_, err := db.dbx.Exec(`CREATE TRIGGER reject_course BEFORE INSERT ON courses
+		WHEN NEW.name = 'rejected' BEGIN SELECT RAISE(ABORT, 'course rejected'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// more courses than fit in one batch, the one at bad is rejected
+func bulkParams(n, bad int) []models.CreateCourseParams {
+	params := make([]models.CreateCourseParams, n)
+	for i := range params {
+		params[i] = models.CreateCourseParams{Name: fmt.Sprintf("course %d", i), Price: 10, Technology: []string{"Go", fmt.Sprintf("tech %d", i%7)}}
+	}
+	if bad >= 0 {
+		params[bad].Name = "rejected"
+	}
+	return params
+}
+
+func countCourses(t *testing.T, db Interface) int {
+	t.Helper()
+	page, err := db.GetAll(context.Background(), models.ListCoursesParams{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Total
+}
+
+func TestCreateManyAtomic(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		results, err := db.CreateMany(ctx, bulkParams(2*insertBatchSize+50, -1), true)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i, result := range results {
+			got, err := db.GetByID(ctx, parseTestID(t, result.Course.Id), false)
+			if err != nil || got.Name != fmt.Sprintf("course %d", i) || !slices.Equal(got.Technology, []string{"Go", fmt.Sprintf("tech %d", i%7)}) {
+				t.Fatalf("%s: course %d read back as %+v, %v", name, i, got, err)
+			}
+		}
+	}
+}
+
+func TestCreateManyAtomicNamesTheFailingCourse(t *testing.T) {
+	db := rejectingSQLite(t)
+	bad := insertBatchSize + 30
+	_, err := db.CreateMany(context.Background(), bulkParams(2*insertBatchSize+50, bad), true)
+	var itemErr *BulkItemError
+	if !errors.As(err, &itemErr) {
+		t.Fatalf("got %v, want a BulkItemError", err)
+	}
+	if itemErr.Index != bad {
+		t.Errorf("failing course %d, want %d", itemErr.Index, bad)
+	}
+	if n := countCourses(t, db); n != 0 {
+		t.Errorf("%d courses created by a failed atomic create", n)
+	}
+}
+
+func TestCreateManyBestEffort(t *testing.T) {
+	db := rejectingSQLite(t)
+	bad := insertBatchSize + 30
+	results, err := db.CreateMany(context.Background(), bulkParams(2*insertBatchSize+50, bad), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if (i == bad) != (result.Err != nil) {
+			t.Errorf("course %d: error %v", i, result.Err)
+		}
+	}
+	if n := countCourses(t, db); n != len(results)-1 {
+		t.Errorf("%d courses created, want all but the rejected one", n)
+	}
+}
+
+// technologies are linked in statements of technologyBatchSize rows, however
+// many one batch of courses has
+func TestLinkTechnologiesInChunks(t *testing.T) {
+	ctx := context.Background()
+	db := backends(t)["sqlite"]
+	technology := make([]string, 2*technologyBatchSize+10)
+	for i := range technology {
+		technology[i] = fmt.Sprintf("tech %d", i)
+	}
+	params := []models.CreateCourseParams{
+		{Name: "many", Price: 10, Technology: technology},
+		{Name: "shared", Price: 10, Technology: technology[technologyBatchSize-5 : technologyBatchSize+5]},
+	}
+	results, err := db.CreateMany(ctx, params, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		got, err := db.GetByID(ctx, parseTestID(t, result.Course.Id), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got.Technology, params[i].Technology) {
+			t.Errorf("%s: %d technologies read back, want %d in order", got.Name, len(got.Technology), len(params[i].Technology))
+		}
+	}
+}
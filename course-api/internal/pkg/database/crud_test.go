@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+// every backend behaves the same through Interface
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		course := mustCreate(t, db, "Go", 10, "Go", "gRPC", "Go")
+		if course.Version != 1 || !slices.Equal(course.Technology, []string{"Go", "gRPC"}) {
+			t.Errorf("%s: created %+v, want version 1 and technologies deduplicated in order", name, course)
+		}
+		id := parseTestID(t, course.Id)
+		got, err := db.GetByID(ctx, id, false)
+		if err != nil || !models.SameContent(got, course) {
+			t.Errorf("%s: read back %+v, %v", name, got, err)
+		}
+
+		update := models.UpdateCourseParams{Name: "Go 2", Price: 12, Technology: []string{"gRPC", "Go"}}
+		if _, err := db.Update(ctx, id, update, 2); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("%s: update at a future version: %v, want ErrVersionMismatch", name, err)
+		}
+		updated, err := db.Update(ctx, id, update, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if updated.Version != 2 || updated.Name != "Go 2" || !slices.Equal(updated.Technology, []string{"gRPC", "Go"}) {
+			t.Errorf("%s: updated %+v", name, updated)
+		}
+
+		patched, err := db.Patch(ctx, id, models.MergePatch(`{"price": 15}`), 0)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if patched.Version != 3 || patched.Price != 15 || patched.Name != "Go 2" {
+			t.Errorf("%s: patched %+v", name, patched)
+		}
+		if _, err := db.Patch(ctx, id, models.MergePatch(`{"price": -1}`), 0); !errors.As(err, new(models.ValidationError)) {
+			t.Errorf("%s: invalid patch: %v, want a ValidationError", name, err)
+		}
+		// a patch that changes nothing keeps the version
+		same, err := db.Patch(ctx, id, models.MergePatch(`{"price": 15}`), 3)
+		if err != nil || same.Version != 3 {
+			t.Errorf("%s: no-op patch: %+v, %v, want version 3", name, same, err)
+		}
+	}
+}
+
+// the memory store hands out copies, callers cannot change what it holds
+func TestMemoryStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryStore()
+	course := mustCreate(t, db, "Go", 10, "Go")
+	course.Technology[0] = "changed"
+	got, err := db.GetByID(ctx, parseTestID(t, course.Id), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Technology[0] = "changed too"
+	page, err := db.GetAll(ctx, models.ListCoursesParams{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Courses[0].Technology[0] != "Go" {
+		t.Errorf("stored technologies changed to %v through a returned course", page.Courses[0].Technology)
+	}
+}
+
+// concurrent writers neither race nor lose courses, sqlite included where
+// writes queue on the busy timeout
+func TestConcurrentCreates(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := db.Create(ctx, models.CreateCourseParams{Name: "Go", Price: 10, Technology: []string{"Go"}})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+		if page, err := db.GetAll(ctx, models.ListCoursesParams{Limit: 1}); err != nil || page.Total != 20 {
+			t.Errorf("%s: %d courses, %v, want 20", name, page.Total, err)
+		}
+	}
+}
@@ -2,20 +2,28 @@ package database
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
-
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/course-api/internal/pkg/logging"
+	"github.com/course-api/internal/pkg/migrations"
 	"github.com/course-api/internal/pkg/models"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
+// CoursesDBSession is the mysql backed course repository. It holds one
+// connection pool for the lifetime of the process; *sqlx.DB is safe for
+// concurrent use so every request shares it.
 type CoursesDBSession struct {
+	sqlAPIKeys
+	sqlTechnologies
 	DatabaseUrl string
 	dbx         *sqlx.DB
 }
@@ -24,209 +32,318 @@ type CoursesDBSession struct {
 // GetContext
 const driverName = "mysql"
 
-type Interface interface {
-	GetAll(ctx context.Context) ([]models.Course, error)
-	GetByID(ctx context.Context, id uuid.UUID) (models.Course, error)
-	Create(ctx context.Context, createParams models.CreateCourseParams) error
-	Update(ctx context.Context, id uuid.UUID, updateParams models.UpdateCourseParams) (models.Course, error)
-	Delete(ctx context.Context, id uuid.UUID) error
-}
-
-func NewCoursesDBSession(url string) *CoursesDBSession {
-	return &CoursesDBSession{
-		DatabaseUrl: url,
-	}
-}
-
-func (s *CoursesDBSession) Ping(ctx context.Context) error {
-	err := s.connect(ctx)
+// NewCoursesDBSession sets up the pool. No connection is made until the first
+// query, so this does not fail when mysql is still starting up.
+func NewCoursesDBSession(url string, pool PoolConfig) (*CoursesDBSession, error) {
+	cfg, err := mysql.ParseDSN(url)
 	if err != nil {
-		log.Println("Could not connect to db")
+		return nil, err
 	}
-	defer s.close()
-	err = s.dbx.PingContext(ctx)
+	// report matched rather than changed rows so Update can tell a missing id
+	// apart from an update that did not change anything
+	cfg.ClientFoundRows = true
+	// api key timestamps scan into time.Time, stored as utc
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	dbx, err := openTraced(driverName, cfg.FormatDSN(), semconv.DBSystemMySQL)
 	if err != nil {
-		log.Println("PING failed:", err)
-		return err
+		return nil, err
 	}
-	return nil
+	pool.apply(dbx)
+	return &CoursesDBSession{
+		sqlAPIKeys:      sqlAPIKeys{dbx: dbx},
+		sqlTechnologies: sqlTechnologies{dbx: dbx},
+		DatabaseUrl:     url,
+		dbx:             dbx,
+	}, nil
 }
 
-func (s *CoursesDBSession) connect(ctx context.Context) error {
-	dbx, err := sqlx.ConnectContext(ctx, driverName, s.DatabaseUrl)
-	fmt.Println("inside connect method....")
+func (s *CoursesDBSession) Ping(ctx context.Context) error {
+	err := s.dbx.PingContext(ctx)
 	if err != nil {
-		fmt.Println("err is", err)
-		return err
+		logging.FromContext(ctx).Error("mysql ping failed", "err", err)
+		return translateError("ping", err)
 	}
-	s.dbx = dbx
 	return nil
 }
 
-func (s *CoursesDBSession) close() {
-	s.dbx.Close()
+// Close closes the pool, it should only be called on shutdown
+func (s *CoursesDBSession) Close() error {
+	return s.dbx.Close()
 }
 
-func (s *CoursesDBSession) Create(ctx context.Context, Params models.CreateCourseParams) (models.Course, error) {
-	err := s.connect(ctx)
-	if err != nil {
-		log.Println("Could not connect to db")
-		return models.Course{}, err
-	}
-	defer s.close()
-	query := `INSERT INTO courses(id,name,price,technology) VALUES(:id, :name, :price, :technology)`
-	uuidGenerated := uuid.New()
-	// since technology field is a slice need to store this in json encoded way(serialization)
-	technologyJson, err := json.Marshal(Params.Technology)
+func (s *CoursesDBSession) Stats() sql.DBStats {
+	return s.dbx.Stats()
+}
 
-	if err != nil {
-		log.Println("json marshal err")
-		return models.Course{}, errors.New("technology field parse error")
-	}
+func (s *CoursesDBSession) Migrator() (*migrations.Migrator, error) {
+	return migrations.New(s.dbx.DB, migrations.MySQL)
+}
 
+func (s *CoursesDBSession) Create(ctx context.Context, Params models.CreateCourseParams) (models.Course, error) {
+	query := `INSERT INTO courses(id,name,price) VALUES(:id, :name, :price)`
+	uuidGenerated := uuid.New()
 	c := models.CourseDatabase{
-		Id:         uuidGenerated.String(),
-		Name:       Params.Name,
-		Price:      Params.Price,
-		Technology: string(technologyJson),
+		Id:    uuidGenerated.String(),
+		Name:  Params.Name,
+		Price: Params.Price,
 	}
+	technology := uniqueTechnologies(Params.Technology)
 
-	result, err := s.dbx.NamedExecContext(ctx, query, c)
-
+	// the course row and its technologies are written together
+	tx, err := s.dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Course{}, translateError("create course", err)
+	}
+	defer tx.Rollback()
+	result, err := tx.NamedExecContext(ctx, query, c)
 	if err != nil {
-		if strings.Contains(err.Error(), "Error 1062") {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
 			return models.Course{}, &DuplicateKeyError{Id: c.Id}
 		}
-		return models.Course{}, err
+		return models.Course{}, translateError("create course", err)
+	}
+	if err := saveTechnologies(ctx, tx, mysqlDialect, c.Id, technology); err != nil {
+		return models.Course{}, translateError("create course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Course{}, translateError("create course", err)
 	}
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Println("rows updated:", rowsAffected)
-	if rowsAffected > 0 {
-		// convert into struct that can be returned
-		course := models.Course{
-			Id:         c.Id,
-			Name:       c.Name,
-			Technology: Params.Technology,
-			Price:      c.Price,
-		}
-		return course, nil
+	logging.FromContext(ctx).Debug("course inserted", "id", c.Id, "rows", rowsAffected)
+	// convert into struct that can be returned
+	course := models.Course{
+		Id:         c.Id,
+		Name:       c.Name,
+		Technology: technology,
+		Price:      c.Price,
+		Version:    1,
 	}
-	return models.Course{}, nil
+	return course, nil
+}
+
+func (s *CoursesDBSession) CreateMany(ctx context.Context, params []models.CreateCourseParams, atomic bool) ([]BulkResult, error) {
+	return createMany(ctx, s.dbx, mysqlDialect, params, atomic)
+}
+
+func (s *CoursesDBSession) Import(ctx context.Context, courses []models.Course, dryRun bool) (models.ImportSummary, error) {
+	return importCourses(ctx, s.dbx, mysqlDialect, courses, dryRun)
 }
 
-func (s *CoursesDBSession) GetAll(ctx context.Context) ([]models.Course, error) {
-	err := s.connect(ctx)
+func (s *CoursesDBSession) GetAll(ctx context.Context, params models.ListCoursesParams) (models.CoursePage, error) {
+	var coursesDatabase []models.CourseDatabase
+	var total int
+	sort := models.SortWithTiebreak(params.Sort)
+
+	conds, args := listFilter(mysqlDialect, params)
+	err := s.dbx.GetContext(ctx, &total, `SELECT COUNT(*) FROM courses`+where(conds), args...)
 	if err != nil {
-		log.Println("could not connext to db")
-		return nil, err
+		return models.CoursePage{}, translateError("count courses", err)
 	}
-	defer s.close()
-	var coursesDatabase []models.CourseDatabase
-	var courses []models.Course
-	query := `SELECT id, name, price, technology FROM courses`
-	// as technology is stored as json encoded , needed to convert this into []string.
-	// a temp struct to hold values retrieved from db
-	// set the db.course
-	err = s.dbx.SelectContext(ctx, &coursesDatabase, query)
+	if params.Cursor != nil {
+		cond, cursorArgs := keysetCondition(sort, *params.Cursor)
+		conds = append(conds, cond)
+		args = append(args, cursorArgs...)
+	}
+	// one extra row tells us whether there is a next page
+	query := `SELECT id, name, price, version, deleted_at FROM courses` + where(conds) + orderBy(sort) + ` LIMIT ? OFFSET ?`
+	args = append(args, params.Limit+1, params.Offset)
+	err = s.dbx.SelectContext(ctx, &coursesDatabase, query, args...)
 	if err != nil {
-		return nil, err
+		return models.CoursePage{}, translateError("list courses", err)
 	}
-	// iterate over every record and setup the fields of struct
-	for _, item := range coursesDatabase {
-		tech, err := models.ConvertToSlice(item.Technology)
-		if err != nil {
-			log.Println("unmarshsal err:", tech)
-			return nil, err
-		}
-		course := models.Course{
-			Id:         item.Id,
-			Name:       item.Name,
-			Price:      item.Price,
-			Technology: tech,
-		}
-		courses = append(courses, course)
+	hasMore := len(coursesDatabase) > params.Limit
+	if hasMore {
+		coursesDatabase = coursesDatabase[:params.Limit]
 	}
-	return courses, nil
-}
-
-func (s *CoursesDBSession) GetByID(ctx context.Context, id uuid.UUID) (models.Course, error) {
-	err := s.connect(ctx)
+	// technologies of the whole page come in one query
+	courses, err := coursesFromRows(ctx, s.dbx, coursesDatabase)
 	if err != nil {
-		log.Println("could not connect to db")
-		return models.Course{}, err
+		return models.CoursePage{}, translateError("list courses", err)
+	}
+	page := models.CoursePage{Courses: courses, Total: total}
+	if hasMore {
+		page.NextCursor = models.NewCursor(params.Sort, courses[len(courses)-1]).Encode()
 	}
-	defer s.close()
+	return page, nil
+}
+
+func (s *CoursesDBSession) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Course, error) {
 	var courseRow models.CourseDatabase
-	query := `SeLect id, name, price,technology from courses where id=?`
-	err = s.dbx.GetContext(ctx, &courseRow, query, id)
-	if err != nil {
-		log.Println("Error fetching course  err:", err)
-		return models.Course{}, err
+	query := `SeLect id, name, price, version, deleted_at from courses where id=?`
+	if !includeDeleted {
+		query += ` AND ` + notDeleted
 	}
-	// convert
-	technologyVal, err := models.ConvertToSlice(courseRow.Technology)
+	err := s.dbx.GetContext(ctx, &courseRow, query, id)
 	if err != nil {
-		log.Println("Failed to convert Technology field for id:", id)
+		logging.FromContext(ctx).Debug("get course failed", "id", id, "err", err)
+		return models.Course{}, translateError("get course", err)
 	}
-	fetchedCourse := models.Course{
-		Id:         courseRow.Id,
-		Name:       courseRow.Name,
-		Price:      courseRow.Price,
-		Technology: technologyVal,
+	courses, err := coursesFromRows(ctx, s.dbx, []models.CourseDatabase{courseRow})
+	if err != nil {
+		return models.Course{}, translateError("get course", err)
 	}
-	return fetchedCourse, nil
+	return courses[0], nil
 }
 
-func (s *CoursesDBSession) Update(ctx context.Context, id uuid.UUID, updateParams models.UpdateCourseParams) (models.Course, error) {
-	// accepts uuid and all other params. Updates
-	err := s.connect(ctx)
+// Update replaces the course. The version check is part of the UPDATE, so of
+// two editors holding the same version only the first one wins.
+func (s *CoursesDBSession) Update(ctx context.Context, id uuid.UUID, updateParams models.UpdateCourseParams, version int64) (models.Course, error) {
+	cond, condArgs := versionCondition(version)
+	query := `UPDATE courses SET name = ?, price = ?, version = version + 1 WHERE id = ? AND ` + notDeleted + cond
+	args := append([]any{updateParams.Name, updateParams.Price, id.String()}, condArgs...)
+	technology := uniqueTechnologies(updateParams.Technology)
+	tx, err := s.dbx.BeginTxx(ctx, nil)
 	if err != nil {
-		log.Println("could not connect to db")
-		return models.Course{}, err
+		return models.Course{}, translateError("update course", err)
 	}
-	defer s.close()
-	query := `UPDATE courses SET name = :name, price = :price, technology = :technology where id = :id`
-	// convert updated courseParams into CourseDatabase struct
-	technologyBytes, err := json.Marshal(updateParams.Technology)
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		log.Println(err)
-		return models.Course{}, err
+		logging.FromContext(ctx).Error("update course failed", "id", id, "err", err)
+		return models.Course{}, translateError("update course", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	// the version always changes, so a matched row is an affected row
+	if rowsAffected == 0 {
+		return models.Course{}, versionError(ctx, tx, "update course", id, false)
 	}
-	courseData := models.CourseDatabase{
+	if err := saveTechnologies(ctx, tx, mysqlDialect, id.String(), technology); err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	var newVersion int64
+	if err := tx.GetContext(ctx, &newVersion, `SELECT version FROM courses WHERE id = ?`, id.String()); err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	// every column was written, so the params are the stored row
+	updatedCourse := models.Course{
 		Id:         id.String(),
 		Name:       updateParams.Name,
 		Price:      updateParams.Price,
-		Technology: string(technologyBytes),
+		Technology: technology,
+		Version:    newVersion,
 	}
-	result, err := s.dbx.NamedExecContext(ctx, query, courseData)
+	return updatedCourse, nil
+
+}
+
+// Patch applies a partial update. The row is locked while the patch is applied
+// so concurrent writers cannot interleave, and only changed columns are written.
+func (s *CoursesDBSession) Patch(ctx context.Context, id uuid.UUID, patch models.CoursePatch, version int64) (models.Course, error) {
+	tx, err := s.dbx.BeginTxx(ctx, nil)
 	if err != nil {
-		log.Println("Error in updating:", err)
-		return models.Course{}, err
+		return models.Course{}, translateError("patch course", err)
 	}
-	log.Println(result.RowsAffected())
-	updatedCourse, err := s.GetByID(ctx, id)
+	defer tx.Rollback()
 
+	var courseRow models.CourseDatabase
+	err = tx.GetContext(ctx, &courseRow, `SELECT id, name, price, version FROM courses WHERE id = ? AND `+notDeleted+` FOR UPDATE`, id.String())
+	if err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	if version != 0 && courseRow.Version != version {
+		return models.Course{}, &Error{Op: "patch course", Kind: ErrVersionMismatch, Err: fmt.Errorf("course %s is at version %d", id, courseRow.Version)}
+	}
+	courses, err := coursesFromRows(ctx, tx, []models.CourseDatabase{courseRow})
+	if err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	current := courses[0]
+	patched, err := patch.Apply(current)
 	if err != nil {
-		log.Println("error in fetching updated record", err)
 		return models.Course{}, err
 	}
-	return updatedCourse, nil
+	patched.Technology = uniqueTechnologies(patched.Technology)
 
+	var sets []string
+	var args []any
+	if patched.Name != current.Name {
+		sets = append(sets, "name = ?")
+		args = append(args, patched.Name)
+	}
+	if patched.Price != current.Price {
+		sets = append(sets, "price = ?")
+		args = append(args, patched.Price)
+	}
+	technologyChanged := !slices.Equal(patched.Technology, current.Technology)
+	if len(sets) == 0 && !technologyChanged {
+		return current, nil
+	}
+	sets = append(sets, "version = version + 1")
+	query := `UPDATE courses SET ` + strings.Join(sets, ", ") + ` WHERE id = ?`
+	args = append(args, id.String())
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		logging.FromContext(ctx).Error("patch course failed", "id", id, "err", err)
+		return models.Course{}, translateError("patch course", err)
+	}
+	patched.Version = current.Version + 1
+	if technologyChanged {
+		if err := saveTechnologies(ctx, tx, mysqlDialect, id.String(), patched.Technology); err != nil {
+			logging.FromContext(ctx).Error("patch course failed", "id", id, "err", err)
+			return models.Course{}, translateError("patch course", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	return patched, nil
 }
 
-func (s *CoursesDBSession) Delete(ctx context.Context, id uuid.UUID) error {
-	err := s.connect(ctx)
+// Search ranks courses with the FULLTEXT indexes, see mysqlSearchMatches
+func (s *CoursesDBSession) Search(ctx context.Context, params models.SearchParams) (models.SearchPage, error) {
+	matches, args := mysqlSearchMatches(params.Terms)
+	var total int
+	query := `SELECT COUNT(*) FROM (` + matches + `) AS hits
+		JOIN courses AS c ON c.id = hits.id
+		WHERE c.deleted_at IS NULL`
+	err := s.dbx.GetContext(ctx, &total, query, args...)
 	if err != nil {
-		log.Println("could not connect to db")
-		return err
+		return models.SearchPage{}, translateError("search courses", err)
 	}
-	defer s.close()
-	query := `DELETE from courses where id=?`
-	result, err := s.dbx.ExecContext(ctx, query, id.String())
+	var rows []struct {
+		models.CourseDatabase
+		Score float64 `db:"score"`
+	}
+	query = `SELECT c.id, c.name, c.price, c.version, hits.score
+		FROM (` + matches + `) AS hits
+		JOIN courses AS c ON c.id = hits.id
+		WHERE c.deleted_at IS NULL
+		ORDER BY hits.score DESC, c.name, c.id
+		LIMIT ? OFFSET ?`
+	if err := s.dbx.SelectContext(ctx, &rows, query, append(args, params.Limit, params.Offset)...); err != nil {
+		return models.SearchPage{}, translateError("search courses", err)
+	}
+	courseRows := make([]models.CourseDatabase, len(rows))
+	for i, row := range rows {
+		courseRows[i] = row.CourseDatabase
+	}
+	courses, err := coursesFromRows(ctx, s.dbx, courseRows)
 	if err != nil {
-		log.Println("error in deleting", err)
-		return err
+		return models.SearchPage{}, translateError("search courses", err)
 	}
-	log.Println(result)
-	return nil
+	page := models.SearchPage{Hits: make([]models.SearchHit, len(courses)), Total: total}
+	for i, course := range courses {
+		page.Hits[i] = models.SearchHit{Course: course, Score: rows[i].Score}
+	}
+	return page, nil
+}
+
+// Delete marks the course deleted, see softDelete
+func (s *CoursesDBSession) Delete(ctx context.Context, id uuid.UUID, version int64, at time.Time) error {
+	return softDelete(ctx, s.dbx, id, version, at)
+}
+
+func (s *CoursesDBSession) Restore(ctx context.Context, id uuid.UUID, version int64) (models.Course, error) {
+	return restore(ctx, s.dbx, id, version)
+}
+
+func (s *CoursesDBSession) Purge(ctx context.Context, id uuid.UUID) error {
+	return purge(ctx, s.dbx, id)
 }
@@ -1,13 +1,135 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sentinel errors callers can check with errors.Is
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflicting record")
+	ErrInvalidData = errors.New("invalid data")
+	ErrUnavailable = errors.New("database unavailable")
+	// the course changed since the version the caller expected
+	ErrVersionMismatch = errors.New("version mismatch")
+	// restoring or purging a course that was never deleted
+	ErrNotDeleted = errors.New("course is not deleted")
 )
 
+// Error is returned by every CoursesDBSession method that fails. Kind is one
+// of the sentinel errors above (or nil when the failure is not classified) and
+// Err is the underlying driver error.
+type Error struct {
+	Op   string
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Kind == nil {
+		return fmt.Sprintf("%s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %v", e.Op, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
 type DuplicateKeyError struct {
 	Id string
 }
 
 func (e *DuplicateKeyError) Error() string {
-	return fmt.Sprintf("duplicate couse id", e.Id)
+	return fmt.Sprintf("duplicate course id %s", e.Id)
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// mysql server error numbers, see
+// https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+var mysqlErrorKinds = map[uint16]error{
+	1022: ErrConflict, // ER_DUP_KEY
+	1062: ErrConflict, // ER_DUP_ENTRY
+	1451: ErrConflict, // ER_ROW_IS_REFERENCED_2
+	1586: ErrConflict, // ER_DUP_ENTRY_WITH_KEY_NAME
+
+	1048: ErrInvalidData, // ER_BAD_NULL_ERROR
+	1264: ErrInvalidData, // ER_WARN_DATA_OUT_OF_RANGE
+	1265: ErrInvalidData, // WARN_DATA_TRUNCATED
+	1292: ErrInvalidData, // ER_TRUNCATED_WRONG_VALUE
+	1366: ErrInvalidData, // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+	1406: ErrInvalidData, // ER_DATA_TOO_LONG
+	1452: ErrInvalidData, // ER_NO_REFERENCED_ROW_2
+	3140: ErrInvalidData, // ER_INVALID_JSON_TEXT
+	3819: ErrInvalidData, // ER_CHECK_CONSTRAINT_VIOLATED
+
+	1040: ErrUnavailable, // ER_CON_COUNT_ERROR
+	1053: ErrUnavailable, // ER_SERVER_SHUTDOWN
+	1205: ErrUnavailable, // ER_LOCK_WAIT_TIMEOUT
+	1213: ErrUnavailable, // ER_LOCK_DEADLOCK
+	1290: ErrUnavailable, // ER_OPTION_PREVENTS_STATEMENT (read only)
+}
+
+// sqlite extended result codes, see https://www.sqlite.org/rescode.html
+var sqliteErrorKinds = map[int]error{
+	sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY: ErrConflict,
+	sqlite3.SQLITE_CONSTRAINT_UNIQUE:     ErrConflict,
+	sqlite3.SQLITE_CONSTRAINT_NOTNULL:    ErrInvalidData,
+	sqlite3.SQLITE_CONSTRAINT_CHECK:      ErrInvalidData,
+	sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY: ErrInvalidData,
+	sqlite3.SQLITE_TOOBIG:                ErrInvalidData,
+	sqlite3.SQLITE_BUSY:                  ErrUnavailable,
+	sqlite3.SQLITE_LOCKED:                ErrUnavailable,
+	sqlite3.SQLITE_READONLY:              ErrUnavailable,
+	sqlite3.SQLITE_FULL:                  ErrUnavailable,
+	sqlite3.SQLITE_CANTOPEN:              ErrUnavailable,
+}
+
+func isSQLiteDuplicate(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErrorKinds[sqliteErr.Code()] == ErrConflict
+}
+
+// translateError classifies err for the operation op. A nil err stays nil.
+func translateError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *Error
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	var mysqlErr *mysql.MySQLError
+	var sqliteErr *sqlite.Error
+	var netErr net.Error
+	var kind error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		kind = ErrNotFound
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// left to the caller, the request itself gave up
+	case errors.As(err, &mysqlErr):
+		kind = mysqlErrorKinds[mysqlErr.Number]
+	case errors.As(err, &sqliteErr):
+		kind = sqliteErrorKinds[sqliteErr.Code()]
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn),
+		errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		kind = ErrUnavailable
+	}
+	return &Error{Op: op, Kind: kind, Err: err}
 }
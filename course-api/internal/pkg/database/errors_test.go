@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"no rows", sql.ErrNoRows, ErrNotFound},
+		{"duplicate entry", &mysql.MySQLError{Number: 1062}, ErrConflict},
+		{"referenced row", &mysql.MySQLError{Number: 1451}, ErrConflict},
+		{"data too long", &mysql.MySQLError{Number: 1406}, ErrInvalidData},
+		{"check constraint", &mysql.MySQLError{Number: 3819}, ErrInvalidData},
+		{"deadlock", &mysql.MySQLError{Number: 1213}, ErrUnavailable},
+		{"read only", &mysql.MySQLError{Number: 1290}, ErrUnavailable},
+		{"bad connection", driver.ErrBadConn, ErrUnavailable},
+		{"invalid connection", mysql.ErrInvalidConn, ErrUnavailable},
+		{"wrapped", fmt.Errorf("query: %w", &mysql.MySQLError{Number: 1062}), ErrConflict},
+		{"unknown mysql error", &mysql.MySQLError{Number: 1064}, nil},
+		{"memory duplicate", &DuplicateKeyError{Id: "x"}, ErrConflict},
+		{"cancelled", context.Canceled, nil},
+	}
+	sentinels := []error{ErrNotFound, ErrConflict, ErrInvalidData, ErrUnavailable}
+	for _, tt := range tests {
+		err := translateError("get course", tt.err)
+		var dbErr *Error
+		if !errors.As(err, &dbErr) || dbErr.Op != "get course" || !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want an *Error wrapping the original", tt.name, err)
+			continue
+		}
+		for _, sentinel := range sentinels {
+			if errors.Is(err, sentinel) != (sentinel == tt.kind) {
+				t.Errorf("%s: errors.Is(%v) = %v", tt.name, sentinel, !(sentinel == tt.kind))
+			}
+		}
+	}
+	if translateError("op", nil) != nil {
+		t.Error("nil should stay nil")
+	}
+	// classified once, the innermost op wins
+	inner := translateError("get course", sql.ErrNoRows)
+	if got := translateError("update course", inner); got != inner {
+		t.Errorf("got %v, want the error unchanged", got)
+	}
+}
+
+func TestTranslateSQLiteErrors(t *testing.T) {
+	ctx := context.Background()
+	db := backends(t)["sqlite"].(*SQLiteSession)
+	if _, err := db.dbx.ExecContext(ctx, `INSERT INTO technologies (name) VALUES ('Go')`); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		kind  error
+	}{
+		{"unique", `INSERT INTO technologies (name) VALUES ('Go')`, ErrConflict},
+		{"not null", `INSERT INTO technologies (name) VALUES (NULL)`, ErrInvalidData},
+		{"foreign key", `INSERT INTO course_technologies (course_id, technology_id, position) VALUES ('missing', 1, 0)`, ErrInvalidData},
+	}
+	for _, tt := range tests {
+		_, err := db.dbx.ExecContext(ctx, tt.query)
+		if err = translateError("op", err); !errors.Is(err, tt.kind) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.kind)
+		}
+	}
+}
+
+func TestUnknownIDIsNotFound(t *testing.T) {
+	ctx := context.Background()
+	id := parseTestID(t, "00000000-0000-0000-0000-000000000000")
+	for name, db := range backends(t) {
+		if _, err := db.GetByID(ctx, id, false); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: get: %v, want ErrNotFound", name, err)
+		}
+		if err := db.Delete(ctx, id, 0, time.Now()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: delete: %v, want ErrNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+// dialect holds the parts of the queries that differ between databases
+type dialect struct {
+	namePrefixFilter string
+	// appended to INSERT INTO technologies so existing names are skipped
+	ignoreDuplicateTechnology string
+}
+
+var mysqlDialect = dialect{
+	// backslashes are escapes inside mysql string literals
+	namePrefixFilter:          `name LIKE ? ESCAPE '\\'`,
+	ignoreDuplicateTechnology: "ON DUPLICATE KEY UPDATE id = id",
+}
+
+var sqliteDialect = dialect{
+	namePrefixFilter:          `name LIKE ? ESCAPE '\'`,
+	ignoreDuplicateTechnology: "ON CONFLICT (name) DO NOTHING",
+}
+
+// technologyFilter matches courses linked to the technology named ?. Written
+// as IN rather than a correlated EXISTS so both databases start from the
+// technologies name index and the course_technologies technology index
+// instead of scanning courses.
+const technologyFilter = `id IN (SELECT ct.course_id FROM course_technologies AS ct
+	JOIN technologies AS t ON t.id = ct.technology_id
+	WHERE t.name = ?)`
+
+// listFilter builds the WHERE clause shared by the page query and the count
+// query, without the cursor condition.
+func listFilter(d dialect, params models.ListCoursesParams) ([]string, []any) {
+	var conds []string
+	var args []any
+	if !params.IncludeDeleted {
+		conds = append(conds, notDeleted)
+	}
+	if params.Technology != "" {
+		conds = append(conds, technologyFilter)
+		args = append(args, params.Technology)
+	}
+	if params.NamePrefix != "" {
+		conds = append(conds, d.namePrefixFilter)
+		args = append(args, escapeLike(params.NamePrefix)+"%")
+	}
+	if params.MinPrice != nil {
+		conds = append(conds, "price >= ?")
+		args = append(args, *params.MinPrice)
+	}
+	if params.MaxPrice != nil {
+		conds = append(conds, "price <= ?")
+		args = append(args, *params.MaxPrice)
+	}
+	return conds, args
+}
+
+// keysetCondition selects the rows that come after the cursor in the given
+// sort order, e.g. for "price,-name,id":
+// (price > ?) OR (price = ? AND name < ?) OR (price = ? AND name = ? AND id > ?)
+func keysetCondition(sort []models.SortField, cursor models.Cursor) (string, []any) {
+	var ors []string
+	var args []any
+	for i, f := range sort {
+		var ands []string
+		for _, prev := range sort[:i] {
+			ands = append(ands, prev.Field+" = ?")
+			args = append(args, cursor.Value(prev.Field))
+		}
+		op := " > ?"
+		if f.Desc {
+			op = " < ?"
+		}
+		ands = append(ands, f.Field+op)
+		args = append(args, cursor.Value(f.Field))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func orderBy(sort []models.SortField) string {
+	parts := make([]string, 0, len(sort))
+	for _, f := range sort {
+		if f.Desc {
+			parts = append(parts, f.Field+" DESC")
+		} else {
+			parts = append(parts, f.Field+" ASC")
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func escapeLike(val string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(val)
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func listNames(t *testing.T, db Interface, params models.ListCoursesParams) []string {
+	t.Helper()
+	if params.Limit == 0 {
+		params.Limit = 100
+	}
+	page, err := db.GetAll(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(page.Courses))
+	for i, course := range page.Courses {
+		names[i] = course.Name
+	}
+	return names
+}
+
+func price(p float64) *float64 { return &p }
+
+func TestGetAllFilters(t *testing.T) {
+	byName := []models.SortField{{Field: "name"}}
+	tests := []struct {
+		name   string
+		params models.ListCoursesParams
+		want   []string
+	}{
+		{"all", models.ListCoursesParams{Sort: byName}, []string{"Advanced Go", "Go basics", "Learning Rust", "Python"}},
+		{"technology", models.ListCoursesParams{Technology: "Go", Sort: byName}, []string{"Advanced Go", "Go basics"}},
+		{"name prefix, any case", models.ListCoursesParams{NamePrefix: "go", Sort: byName}, []string{"Go basics"}},
+		{"min price", models.ListCoursesParams{MinPrice: price(20), Sort: byName}, []string{"Advanced Go", "Learning Rust"}},
+		{"price range", models.ListCoursesParams{MinPrice: price(10), MaxPrice: price(20), Sort: byName}, []string{"Go basics", "Learning Rust"}},
+		{"price descending", models.ListCoursesParams{Sort: []models.SortField{{Field: "price", Desc: true}, {Field: "name"}}}, []string{"Advanced Go", "Learning Rust", "Go basics", "Python"}},
+		{"offset", models.ListCoursesParams{Limit: 2, Offset: 1, Sort: byName}, []string{"Go basics", "Learning Rust"}},
+	}
+	for backend, db := range backends(t) {
+		mustCreate(t, db, "Go basics", 10, "Go")
+		mustCreate(t, db, "Advanced Go", 30, "Go", "gRPC")
+		mustCreate(t, db, "Learning Rust", 20, "Rust")
+		mustCreate(t, db, "Python", 5, "Python")
+		for _, tt := range tests {
+			if got := listNames(t, db, tt.params); !slices.Equal(got, tt.want) {
+				t.Errorf("%s: %s: got %v, want %v", backend, tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+// walking the cursors visits every course once in order, ties on the sort
+// column broken by id
+func TestGetAllCursor(t *testing.T) {
+	ctx := context.Background()
+	for backend, db := range backends(t) {
+		for _, p := range []float64{10, 20, 10, 30, 10, 20, 10} {
+			mustCreate(t, db, "course", p, "Go")
+		}
+		sort := []models.SortField{{Field: "price", Desc: true}}
+		all := listNames(t, db, models.ListCoursesParams{Sort: sort})
+		params := models.ListCoursesParams{Limit: 3, Sort: sort}
+		var prices []float64
+		seen := map[string]bool{}
+		for pages := 0; ; pages++ {
+			if pages > len(all) {
+				t.Fatalf("%s: the cursor does not move on", backend)
+			}
+			page, err := db.GetAll(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, course := range page.Courses {
+				if seen[course.Id] {
+					t.Errorf("%s: course %s on two pages", backend, course.Id)
+				}
+				seen[course.Id] = true
+				prices = append(prices, course.Price)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor, err := models.DecodeCursor(page.NextCursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params.Cursor = &cursor
+		}
+		if want := []float64{30, 20, 20, 10, 10, 10, 10}; !slices.Equal(prices, want) {
+			t.Errorf("%s: prices %v, want %v", backend, prices, want)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/course-api/internal/pkg/search"
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps courses in a map guarded by a mutex. It behaves like the
+// mysql session (same errors, same filtering and ordering) so it can stand in
+// for it in demos and handler tests.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	courses map[string]models.Course
+	// api keys by id, each with the hash it is looked up by
+	apiKeys map[string]memoryAPIKey
+	// full-text index over courses, updated with them under mu
+	index *search.Index
+}
+
+type memoryAPIKey struct {
+	key  models.APIKey
+	hash string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		courses: make(map[string]models.Course),
+		apiKeys: make(map[string]memoryAPIKey),
+		index:   search.NewIndex(),
+	}
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) Create(ctx context.Context, params models.CreateCourseParams) (models.Course, error) {
+	course := models.Course{
+		Id:         uuid.New().String(),
+		Name:       params.Name,
+		Price:      params.Price,
+		Technology: uniqueTechnologies(params.Technology),
+		Version:    1,
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.courses[course.Id]; ok {
+		return models.Course{}, &DuplicateKeyError{Id: course.Id}
+	}
+	m.courses[course.Id] = course
+	m.index.Put(searchDocument(course))
+	return copyCourse(course), nil
+}
+
+// CreateMany cannot fail part way in memory, so atomic makes no difference
+func (m *MemoryStore) CreateMany(ctx context.Context, params []models.CreateCourseParams, atomic bool) ([]BulkResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	results := make([]BulkResult, len(params))
+	for i, p := range params {
+		course := models.Course{
+			Id:         uuid.New().String(),
+			Name:       p.Name,
+			Price:      p.Price,
+			Technology: uniqueTechnologies(p.Technology),
+			Version:    1,
+		}
+		m.courses[course.Id] = course
+		m.index.Put(searchDocument(course))
+		results[i].Course = copyCourse(course)
+	}
+	return results, nil
+}
+
+func (m *MemoryStore) Import(ctx context.Context, courses []models.Course, dryRun bool) (models.ImportSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summary := models.ImportSummary{DryRun: dryRun, Rows: len(courses)}
+	for i := range courses {
+		if courses[i].Id == "" {
+			courses[i].Id = uuid.New().String()
+		}
+		courses[i].Technology = uniqueTechnologies(courses[i].Technology)
+		if existing, ok := m.courses[courses[i].Id]; ok {
+			courses[i].Version = existing.Version + 1
+			summary.Updated++
+		} else {
+			courses[i].Version = 1
+			summary.Created++
+		}
+	}
+	if dryRun {
+		return summary, nil
+	}
+	for _, course := range courses {
+		course = copyCourse(course)
+		m.courses[course.Id] = course
+		m.index.Put(searchDocument(course))
+	}
+	return summary, nil
+}
+
+func (m *MemoryStore) GetAll(ctx context.Context, params models.ListCoursesParams) (models.CoursePage, error) {
+	m.mu.RLock()
+	var matched []models.Course
+	for _, c := range m.courses {
+		if matchesFilter(c, params) {
+			matched = append(matched, c)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort := models.SortWithTiebreak(params.Sort)
+	slices.SortFunc(matched, func(a, b models.Course) int {
+		return compareCourses(a, b, sort)
+	})
+	total := len(matched)
+	if params.Cursor != nil {
+		// skip everything up to and including the cursor row
+		after := slices.IndexFunc(matched, func(c models.Course) bool {
+			return compareToCursor(c, *params.Cursor, sort) > 0
+		})
+		if after < 0 {
+			after = len(matched)
+		}
+		matched = matched[after:]
+	}
+	matched = matched[min(params.Offset, len(matched)):]
+
+	page := models.CoursePage{Courses: []models.Course{}, Total: total}
+	for i, c := range matched {
+		if i == params.Limit {
+			page.NextCursor = models.NewCursor(params.Sort, matched[i-1]).Encode()
+			break
+		}
+		page.Courses = append(page.Courses, copyCourse(c))
+	}
+	return page, nil
+}
+
+func (m *MemoryStore) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Course, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	course, ok := m.courses[id.String()]
+	if !ok || (course.DeletedAt != nil && !includeDeleted) {
+		return models.Course{}, notFound("get course", id)
+	}
+	return copyCourse(course), nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id uuid.UUID, updateParams models.UpdateCourseParams, version int64) (models.Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, err := m.expect("update course", id, version)
+	if err != nil {
+		return models.Course{}, err
+	}
+	course := models.Course{
+		Id:         id.String(),
+		Name:       updateParams.Name,
+		Price:      updateParams.Price,
+		Technology: uniqueTechnologies(updateParams.Technology),
+		Version:    current.Version + 1,
+	}
+	m.courses[course.Id] = course
+	m.index.Put(searchDocument(course))
+	return copyCourse(course), nil
+}
+
+func (m *MemoryStore) Patch(ctx context.Context, id uuid.UUID, patch models.CoursePatch, version int64) (models.Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, err := m.expect("patch course", id, version)
+	if err != nil {
+		return models.Course{}, err
+	}
+	patched, err := patch.Apply(copyCourse(current))
+	if err != nil {
+		return models.Course{}, err
+	}
+	patched.Technology = uniqueTechnologies(patched.Technology)
+	if models.SameContent(patched, current) {
+		return copyCourse(current), nil
+	}
+	patched.Version = current.Version + 1
+	m.courses[patched.Id] = patched
+	m.index.Put(searchDocument(patched))
+	return copyCourse(patched), nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id uuid.UUID, version int64, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	course, err := m.expect("delete course", id, version)
+	if err != nil {
+		return err
+	}
+	course.DeletedAt = &at
+	course.Version++
+	m.courses[course.Id] = course
+	m.index.Remove(course.Id)
+	return nil
+}
+
+func (m *MemoryStore) Restore(ctx context.Context, id uuid.UUID, version int64) (models.Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	course, err := m.expectDeleted("restore course", id)
+	if err != nil {
+		return models.Course{}, err
+	}
+	if version != 0 && course.Version != version {
+		return models.Course{}, &Error{Op: "restore course", Kind: ErrVersionMismatch, Err: fmt.Errorf("course %s is at version %d", id, course.Version)}
+	}
+	course.DeletedAt = nil
+	course.Version++
+	m.courses[course.Id] = course
+	m.index.Put(searchDocument(course))
+	return copyCourse(course), nil
+}
+
+func (m *MemoryStore) Purge(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.expectDeleted("purge course", id); err != nil {
+		return err
+	}
+	delete(m.courses, id.String())
+	return nil
+}
+
+func (m *MemoryStore) Search(ctx context.Context, params models.SearchParams) (models.SearchPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	hits := m.index.Search(params.Terms)
+	page := models.SearchPage{Hits: []models.SearchHit{}, Total: len(hits)}
+	for _, hit := range pageHits(hits, params) {
+		page.Hits = append(page.Hits, models.SearchHit{Course: copyCourse(m.courses[hit.ID]), Score: hit.Score})
+	}
+	return page, nil
+}
+
+func (m *MemoryStore) ListTechnologies(ctx context.Context) ([]models.Technology, error) {
+	counts := map[string]int{}
+	m.mu.RLock()
+	for _, c := range m.courses {
+		if c.DeletedAt != nil {
+			continue
+		}
+		for _, name := range c.Technology {
+			counts[name]++
+		}
+	}
+	m.mu.RUnlock()
+	technologies := make([]models.Technology, 0, len(counts))
+	for name, count := range counts {
+		technologies = append(technologies, models.Technology{Name: name, CourseCount: count})
+	}
+	slices.SortFunc(technologies, func(a, b models.Technology) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return technologies, nil
+}
+
+func (m *MemoryStore) CreateAPIKey(ctx context.Context, key models.APIKey, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.apiKeys {
+		if k.key.Id == key.Id || k.hash == hash {
+			return &Error{Op: "create api key", Kind: ErrConflict, Err: fmt.Errorf("api key %s already exists", key.Id)}
+		}
+	}
+	m.apiKeys[key.Id] = memoryAPIKey{key: copyAPIKey(key), hash: hash}
+	return nil
+}
+
+func (m *MemoryStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	m.mu.RLock()
+	keys := make([]models.APIKey, 0, len(m.apiKeys))
+	for _, k := range m.apiKeys {
+		keys = append(keys, copyAPIKey(k.key))
+	}
+	m.mu.RUnlock()
+	slices.SortFunc(keys, func(a, b models.APIKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Id, b.Id))
+	})
+	return keys, nil
+}
+
+func (m *MemoryStore) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, k := range m.apiKeys {
+		if k.hash == hash {
+			return copyAPIKey(k.key), nil
+		}
+	}
+	return models.APIKey{}, &Error{Op: "get api key", Kind: ErrNotFound, Err: fmt.Errorf("no api key with that hash")}
+}
+
+func (m *MemoryStore) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.apiKeys[id.String()]
+	if !ok || k.key.RevokedAt != nil {
+		return models.APIKey{}, apiKeyNotFound("rotate api key", id)
+	}
+	k.hash = hash
+	k.key.Prefix = prefix
+	m.apiKeys[id.String()] = k
+	return copyAPIKey(k.key), nil
+}
+
+func (m *MemoryStore) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.apiKeys[id.String()]
+	if !ok {
+		return models.APIKey{}, apiKeyNotFound("revoke api key", id)
+	}
+	if k.key.RevokedAt == nil {
+		k.key.RevokedAt = &at
+		m.apiKeys[id.String()] = k
+	}
+	return copyAPIKey(k.key), nil
+}
+
+func (m *MemoryStore) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.apiKeys[id.String()]; ok {
+		k.key.LastUsedAt = &at
+		m.apiKeys[id.String()] = k
+	}
+	return nil
+}
+
+// copyAPIKey keeps the scopes slice private to the store, the time pointers
+// are never written through
+func copyAPIKey(k models.APIKey) models.APIKey {
+	k.Scopes = slices.Clone(k.Scopes)
+	return k
+}
+
+// expect returns the course when it is not deleted and at version, or any
+// version for 0. m.mu must be held.
+func (m *MemoryStore) expect(op string, id uuid.UUID, version int64) (models.Course, error) {
+	course, ok := m.courses[id.String()]
+	if !ok || course.DeletedAt != nil {
+		return models.Course{}, notFound(op, id)
+	}
+	if version != 0 && course.Version != version {
+		return models.Course{}, &Error{Op: op, Kind: ErrVersionMismatch, Err: fmt.Errorf("course %s is at version %d", id, course.Version)}
+	}
+	return course, nil
+}
+
+// expectDeleted returns the course when it is deleted. m.mu must be held.
+func (m *MemoryStore) expectDeleted(op string, id uuid.UUID) (models.Course, error) {
+	course, ok := m.courses[id.String()]
+	if !ok {
+		return models.Course{}, notFound(op, id)
+	}
+	if course.DeletedAt == nil {
+		return models.Course{}, &Error{Op: op, Kind: ErrNotDeleted, Err: fmt.Errorf("course %s is not deleted", id)}
+	}
+	return course, nil
+}
+
+func notFound(op string, id uuid.UUID) error {
+	return &Error{Op: op, Kind: ErrNotFound, Err: fmt.Errorf("no course with id %s", id)}
+}
+
+// copyCourse makes sure callers never share the technology slice with the store
+func copyCourse(c models.Course) models.Course {
+	c.Technology = slices.Clone(c.Technology)
+	return c
+}
+
+// matchesFilter mirrors listFilter. Names compare case-insensitively like the
+// default mysql collation, technologies compare exactly like the utf8mb4_bin
+// technologies.name column.
+func matchesFilter(c models.Course, params models.ListCoursesParams) bool {
+	if c.DeletedAt != nil && !params.IncludeDeleted {
+		return false
+	}
+	if params.Technology != "" && !slices.Contains(c.Technology, params.Technology) {
+		return false
+	}
+	if params.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(c.Name), strings.ToLower(params.NamePrefix)) {
+		return false
+	}
+	if params.MinPrice != nil && c.Price < *params.MinPrice {
+		return false
+	}
+	if params.MaxPrice != nil && c.Price > *params.MaxPrice {
+		return false
+	}
+	return true
+}
+
+func compareCourses(a, b models.Course, sort []models.SortField) int {
+	return compareToCursor(a, models.NewCursor(nil, b), sort)
+}
+
+func compareToCursor(c models.Course, cursor models.Cursor, sort []models.SortField) int {
+	for _, f := range sort {
+		var order int
+		switch f.Field {
+		case "name":
+			order = strings.Compare(strings.ToLower(c.Name), strings.ToLower(cursor.Name))
+		case "price":
+			order = cmp.Compare(c.Price, cursor.Price)
+		default:
+			order = strings.Compare(c.Id, cursor.Id)
+		}
+		if f.Desc {
+			order = -order
+		}
+		if order != 0 {
+			return order
+		}
+	}
+	return 0
+}
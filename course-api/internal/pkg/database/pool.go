@@ -0,0 +1,22 @@
+package database
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PoolConfig sizes the connection pool shared by all requests
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (p PoolConfig) apply(dbx *sqlx.DB) {
+	dbx.SetMaxOpenConns(p.MaxOpenConns)
+	dbx.SetMaxIdleConns(p.MaxIdleConns)
+	dbx.SetConnMaxLifetime(p.ConnMaxLifetime)
+	dbx.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPoolConfig(t *testing.T) {
+	pool := PoolConfig{MaxOpenConns: 7, MaxIdleConns: 3, ConnMaxLifetime: time.Minute}
+	// no connection is made until the first query, so this needs no server
+	mysql, err := NewCoursesDBSession("user:pass@tcp(127.0.0.1:3306)/courses", pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mysql.Close()
+	sqlite, err := NewSQLiteSession(context.Background(), filepath.Join(t.TempDir(), "test.db"), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	for name, db := range map[string]Pooled{"mysql": mysql, "sqlite": sqlite} {
+		if got := db.Stats().MaxOpenConnections; got != 7 {
+			t.Errorf("%s: %d max open connections, want 7", name, got)
+		}
+	}
+}
+
+func TestBadMySQLURL(t *testing.T) {
+	if _, err := NewCoursesDBSession("not a dsn", PoolConfig{}); err == nil {
+		t.Error("NewCoursesDBSession accepted a malformed dsn")
+	}
+}
+
+// the pool is opened once and reused: a query does not close it
+func TestPoolOutlivesQueries(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewSQLiteSession(ctx, filepath.Join(t.TempDir(), "test.db"), PoolConfig{MaxOpenConns: 2, MaxIdleConns: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, db, "Go", 10)
+	if _, err := db.ListTechnologies(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(ctx); err != nil {
+		t.Errorf("ping after queries: %v", err)
+	}
+	if got := db.Stats().OpenConnections; got == 0 {
+		t.Error("no connection kept open after queries")
+	}
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/course-api/internal/pkg/search"
+	"github.com/jmoiron/sqlx"
+)
+
+// searchIndex wraps the in-process index for backends without full-text
+// search. It is filled by load on the first search and kept current by
+// put/remove, which the write methods call once their change is committed.
+// Until then put and remove are no-ops, load reads the changes anyway.
+type searchIndex struct {
+	mu    sync.Mutex
+	index *search.Index
+	load  func(ctx context.Context) ([]models.Course, error)
+}
+
+// get returns the index, loading it first if needed
+func (s *searchIndex) get(ctx context.Context) (*search.Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil {
+		return s.index, nil
+	}
+	courses, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := search.NewIndex()
+	for _, course := range courses {
+		index.Put(searchDocument(course))
+	}
+	s.index = index
+	return index, nil
+}
+
+func (s *searchIndex) put(course models.Course) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil {
+		s.index.Put(searchDocument(course))
+	}
+}
+
+func (s *searchIndex) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index != nil {
+		s.index.Remove(id)
+	}
+}
+
+func searchDocument(course models.Course) search.Document {
+	return search.Document{ID: course.Id, Name: course.Name, Technology: course.Technology}
+}
+
+// pageHits cuts the requested page out of hits
+func pageHits(hits []search.Hit, params models.SearchParams) []search.Hit {
+	hits = hits[min(params.Offset, len(hits)):]
+	return hits[:min(params.Limit, len(hits))]
+}
+
+// searchCourses runs the search on the in-process index and reads the
+// courses of the page from q
+func searchCourses(ctx context.Context, q sqlx.QueryerContext, index *search.Index, params models.SearchParams) (models.SearchPage, error) {
+	hits := index.Search(params.Terms)
+	page := models.SearchPage{Hits: []models.SearchHit{}, Total: len(hits)}
+	hits = pageHits(hits, params)
+	if len(hits) == 0 {
+		return page, nil
+	}
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	query, args, err := sqlx.In(`SELECT id, name, price, version FROM courses WHERE id IN (?) AND `+notDeleted, ids)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	var rows []models.CourseDatabase
+	if err := sqlx.SelectContext(ctx, q, &rows, query, args...); err != nil {
+		return models.SearchPage{}, err
+	}
+	courses, err := coursesFromRows(ctx, q, rows)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	byID := make(map[string]models.Course, len(courses))
+	for _, course := range courses {
+		byID[course.Id] = course
+	}
+	for _, hit := range hits {
+		// deleted after the index was searched
+		course, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		page.Hits = append(page.Hits, models.SearchHit{Course: course, Score: hit.Score})
+	}
+	return page, nil
+}
+
+// the defaults of innodb_ft_min_token_size and innodb_ft_server_stopword_table:
+// words the FULLTEXT indexes do not hold
+const mysqlMinTokenSize = 3
+
+var mysqlStopwords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "com": true, "de": true, "en": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"la": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "when": true,
+	"where": true, "who": true, "will": true, "with": true, "und": true,
+	"www": true,
+}
+
+func mysqlIndexed(term string) bool {
+	return utf8.RuneCountInString(term) >= mysqlMinTokenSize && !mysqlStopwords[term]
+}
+
+// mysqlWordPattern matches term as a whole word, the way search.Terms splits
+// words. Terms are letters and digits only, nothing to escape.
+func mysqlWordPattern(term string) string {
+	return `(^|[^\p{L}\p{N}])` + term + `([^\p{L}\p{N}]|$)`
+}
+
+// mysqlSearchMatches scores every course matching one of terms, so MySQL finds
+// what the in-process index finds. Name matches come from ft_courses_name and
+// technology matches from ft_technologies_search_name in BOOLEAN MODE, which
+// matches any of the words like the index does; words the indexes leave out
+// fall back to a regular expression per word, scored by its weight alone.
+func mysqlSearchMatches(terms []string) (string, []any) {
+	var indexed []string
+	var parts []string
+	var args []any
+	for _, term := range terms {
+		if mysqlIndexed(term) {
+			indexed = append(indexed, term)
+			continue
+		}
+		pattern := mysqlWordPattern(term)
+		parts = append(parts,
+			`SELECT id, ? AS score FROM courses WHERE REGEXP_LIKE(name, ?, 'i')`,
+			`SELECT ct.course_id, ?
+		FROM technologies AS t
+		JOIN course_technologies AS ct ON ct.technology_id = t.id
+		WHERE REGEXP_LIKE(t.search_name, ?, 'i')`)
+		args = append(args, search.NameWeight, pattern, search.TechnologyWeight, pattern)
+	}
+	if len(indexed) > 0 {
+		against := strings.Join(indexed, " ")
+		parts = append([]string{
+			`SELECT id, MATCH (name) AGAINST (? IN BOOLEAN MODE) * ? AS score
+		FROM courses
+		WHERE MATCH (name) AGAINST (? IN BOOLEAN MODE)`,
+			`SELECT ct.course_id, MATCH (t.search_name) AGAINST (? IN BOOLEAN MODE) * ?
+		FROM technologies AS t
+		JOIN course_technologies AS ct ON ct.technology_id = t.id
+		WHERE MATCH (t.search_name) AGAINST (? IN BOOLEAN MODE)`,
+		}, parts...)
+		args = append([]any{against, search.NameWeight, against, against, search.TechnologyWeight, against}, args...)
+	}
+	query := `SELECT id, SUM(score) AS score FROM (
+		` + strings.Join(parts, `
+		UNION ALL
+		`) + `
+	) AS matches
+	GROUP BY id`
+	return query, args
+}
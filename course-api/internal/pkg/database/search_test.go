@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/course-api/internal/pkg/search"
+)
+
+func searchNames(t *testing.T, db Interface, q string) []string {
+	t.Helper()
+	page, err := db.Search(context.Background(), models.SearchParams{Terms: search.Terms(q), Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(page.Hits))
+	for i, hit := range page.Hits {
+		names[i] = hit.Course.Name
+	}
+	if page.Total != len(names) {
+		t.Errorf("%q: total %d for %d hits", q, page.Total, len(names))
+	}
+	return names
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		// name matches count double, then ordered by name
+		{"django", []string{"Django for beginners", "Web apps"}},
+		{"go", []string{"Go in practice", "Microservices"}},
+		{"the", []string{"The C language"}},
+		{"c", []string{"The C language"}},
+		{"django celery", []string{"Web apps", "Django for beginners"}},
+		{"rust", nil},
+		// whole words only
+		{"djan", nil},
+	}
+	for name, db := range backends(t) {
+		mustCreate(t, db, "Django for beginners", 10, "Python")
+		mustCreate(t, db, "Web apps", 10, "Django", "Celery")
+		mustCreate(t, db, "Go in practice", 10)
+		mustCreate(t, db, "Microservices", 10, "Go", "gRPC")
+		mustCreate(t, db, "The C language", 10)
+		for _, tt := range tests {
+			if got := searchNames(t, db, tt.q); !slices.Equal(got, tt.want) {
+				t.Errorf("%s: search %q = %v, want %v", name, tt.q, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSearchSkipsDeletedCourses(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		course := mustCreate(t, db, "Go in practice", 10)
+		if err := db.Delete(ctx, parseTestID(t, course.Id), 0, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+			t.Fatal(err)
+		}
+		if got := searchNames(t, db, "go"); len(got) != 0 {
+			t.Errorf("%s: found deleted courses %v", name, got)
+		}
+	}
+}
+
+// words InnoDB does not index are matched by a regular expression instead
+func TestMySQLSearchMatches(t *testing.T) {
+	tests := []struct {
+		terms     []string
+		fulltext  string
+		fallbacks []string
+	}{
+		{[]string{"django", "celery"}, "django celery", nil},
+		{[]string{"go"}, "", []string{"go"}},
+		{[]string{"learn", "go", "the", "web"}, "learn web", []string{"go", "the"}},
+		{[]string{"ñu"}, "", []string{"ñu"}},
+		{[]string{"año"}, "año", nil},
+	}
+	for _, tt := range tests {
+		query, args := mysqlSearchMatches(tt.terms)
+		if n := strings.Count(query, "?"); n != len(args) {
+			t.Errorf("%v: %d placeholders for %d args", tt.terms, n, len(args))
+		}
+		if strings.Contains(query, "NATURAL LANGUAGE") {
+			t.Errorf("%v: natural language mode drops words in half the rows", tt.terms)
+		}
+		if tt.fulltext == "" && strings.Contains(query, "MATCH") {
+			t.Errorf("%v: uses the FULLTEXT indexes for unindexed words", tt.terms)
+		}
+		if tt.fulltext != "" && (!strings.Contains(query, "IN BOOLEAN MODE") || args[0] != tt.fulltext) {
+			t.Errorf("%v: full-text search for %v, want %q", tt.terms, args[0], tt.fulltext)
+		}
+		var fallbacks []string
+		for _, term := range tt.terms {
+			if slices.Contains(args, any(mysqlWordPattern(term))) {
+				fallbacks = append(fallbacks, term)
+			}
+		}
+		if !slices.Equal(fallbacks, tt.fallbacks) {
+			t.Errorf("%v: regular expressions for %v, want %v", tt.terms, fallbacks, tt.fallbacks)
+		}
+	}
+}
+
+// the fallback pattern finds a word where search.Terms would split it out
+func TestMySQLWordPattern(t *testing.T) {
+	for _, text := range []string{"Go", "go", "Learn Go!", "Go-kit", "gopher", "Django", "ego go", "C++", "cgo", "go2", "über go"} {
+		for _, term := range []string{"go", "c"} {
+			// ICU and Go share \p{L} and \p{N}
+			re := regexp.MustCompile("(?i)" + mysqlWordPattern(term))
+			want := slices.Contains(search.Terms(text), term)
+			if got := re.MatchString(text); got != want {
+				t.Errorf("%q matches %q: %v, want %v", mysqlWordPattern(term), text, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// notDeleted narrows a query on courses to the ones that were not deleted
+const notDeleted = `deleted_at IS NULL`
+
+// softDelete implements Delete for both sql backends. The row and its
+// technologies stay so the course can be restored.
+func softDelete(ctx context.Context, dbx *sqlx.DB, id uuid.UUID, version int64, at time.Time) error {
+	cond, condArgs := versionCondition(version)
+	query := `UPDATE courses SET deleted_at = ?, version = version + 1 WHERE id = ? AND ` + notDeleted + cond
+	result, err := dbx.ExecContext(ctx, query, append([]any{at, id.String()}, condArgs...)...)
+	if err != nil {
+		return translateError("delete course", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return translateError("delete course", err)
+	}
+	if rowsAffected == 0 {
+		return versionError(ctx, dbx, "delete course", id, false)
+	}
+	return nil
+}
+
+// restore implements Restore for both sql backends
+func restore(ctx context.Context, dbx *sqlx.DB, id uuid.UUID, version int64) (models.Course, error) {
+	tx, err := dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Course{}, translateError("restore course", err)
+	}
+	defer tx.Rollback()
+	cond, condArgs := versionCondition(version)
+	query := `UPDATE courses SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL` + cond
+	result, err := tx.ExecContext(ctx, query, append([]any{id.String()}, condArgs...)...)
+	if err != nil {
+		return models.Course{}, translateError("restore course", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return models.Course{}, translateError("restore course", err)
+	}
+	if rowsAffected == 0 {
+		return models.Course{}, versionError(ctx, tx, "restore course", id, true)
+	}
+	var row models.CourseDatabase
+	if err := tx.GetContext(ctx, &row, `SELECT id, name, price, version FROM courses WHERE id = ?`, id.String()); err != nil {
+		return models.Course{}, translateError("restore course", err)
+	}
+	courses, err := coursesFromRows(ctx, tx, []models.CourseDatabase{row})
+	if err != nil {
+		return models.Course{}, translateError("restore course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Course{}, translateError("restore course", err)
+	}
+	return courses[0], nil
+}
+
+// purge implements Purge for both sql backends, the course_technologies rows
+// go with the course through the foreign key
+func purge(ctx context.Context, dbx *sqlx.DB, id uuid.UUID) error {
+	result, err := dbx.ExecContext(ctx, `DELETE FROM courses WHERE id = ? AND deleted_at IS NOT NULL`, id.String())
+	if err != nil {
+		return translateError("purge course", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return translateError("purge course", err)
+	}
+	if rowsAffected == 0 {
+		return versionError(ctx, dbx, "purge course", id, true)
+	}
+	return nil
+}
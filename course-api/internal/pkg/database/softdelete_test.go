@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	for name, db := range backends(t) {
+		course := mustCreate(t, db, "Go in practice", 10, "Go")
+		mustCreate(t, db, "Rust in practice", 10, "Rust")
+		id := parseTestID(t, course.Id)
+
+		if err := db.Delete(ctx, id, course.Version+1, at); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("%s: delete at the wrong version: %v, want ErrVersionMismatch", name, err)
+		}
+		if err := db.Delete(ctx, id, course.Version, at); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if _, err := db.GetByID(ctx, id, false); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: get a deleted course: %v, want ErrNotFound", name, err)
+		}
+		deleted, err := db.GetByID(ctx, id, true)
+		if err != nil || deleted.DeletedAt == nil || !deleted.DeletedAt.Equal(at) || deleted.Version != course.Version+1 {
+			t.Errorf("%s: deleted course read back as %+v, %v", name, deleted, err)
+		}
+		if page, err := db.GetAll(ctx, models.ListCoursesParams{Limit: 10}); err != nil || page.Total != 1 {
+			t.Errorf("%s: %d courses listed, %v, want the one not deleted", name, page.Total, err)
+		}
+		if page, err := db.GetAll(ctx, models.ListCoursesParams{Limit: 10, IncludeDeleted: true}); err != nil || page.Total != 2 {
+			t.Errorf("%s: %d courses listed with include_deleted, %v, want 2", name, page.Total, err)
+		}
+		technologies, err := db.ListTechnologies(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, technology := range technologies {
+			if technology.Name == "Go" && technology.CourseCount != 0 {
+				t.Errorf("%s: Go counted in %d courses, the only one is deleted", name, technology.CourseCount)
+			}
+		}
+
+		update := models.UpdateCourseParams{Name: "Go", Price: 5, Technology: []string{"Go"}}
+		if _, err := db.Update(ctx, id, update, 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: update a deleted course: %v, want ErrNotFound", name, err)
+		}
+		if err := db.Delete(ctx, id, 0, at); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: delete twice: %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestRestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		course := mustCreate(t, db, "Go in practice", 10, "Go")
+		id := parseTestID(t, course.Id)
+		if _, err := db.Restore(ctx, id, 0); !errors.Is(err, ErrNotDeleted) {
+			t.Errorf("%s: restore a live course: %v, want ErrNotDeleted", name, err)
+		}
+		if err := db.Purge(ctx, id); !errors.Is(err, ErrNotDeleted) {
+			t.Errorf("%s: purge a live course: %v, want ErrNotDeleted", name, err)
+		}
+
+		if err := db.Delete(ctx, id, 0, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Restore(ctx, id, course.Version); !errors.Is(err, ErrVersionMismatch) {
+			t.Errorf("%s: restore at the version before the delete: %v, want ErrVersionMismatch", name, err)
+		}
+		restored, err := db.Restore(ctx, id, course.Version+1)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if restored.DeletedAt != nil || restored.Version != course.Version+2 || restored.Technology[0] != "Go" {
+			t.Errorf("%s: restored %+v", name, restored)
+		}
+		if got := searchNames(t, db, "go"); len(got) != 1 {
+			t.Errorf("%s: search finds %v after the restore", name, got)
+		}
+
+		if err := db.Delete(ctx, id, 0, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Purge(ctx, id); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := db.GetByID(ctx, id, true); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: purged course: %v, want ErrNotFound", name, err)
+		}
+		if _, err := db.Restore(ctx, id, 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: restore a purged course: %v, want ErrNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/course-api/internal/pkg/logging"
+	"github.com/course-api/internal/pkg/migrations"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteDriverName = "sqlite"
+
+// sqlite connection settings: wait on locks instead of failing right away and
+// let readers run alongside the single writer
+const sqlitePragmas = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate"
+
+// SQLiteSession stores courses in a single sqlite file, with the same tables
+// as mysql.
+type SQLiteSession struct {
+	sqlAPIKeys
+	sqlTechnologies
+	Path string
+	dbx  *sqlx.DB
+	// sqlite has no full-text index here, search runs in process
+	search searchIndex
+}
+
+// NewSQLiteSession opens (and creates if needed) the database file at path.
+// The schema comes from the migrations package.
+func NewSQLiteSession(ctx context.Context, path string, pool PoolConfig) (*SQLiteSession, error) {
+	dsn := "file:" + path
+	if strings.Contains(path, "?") {
+		dsn += "&" + sqlitePragmas
+	} else {
+		dsn += "?" + sqlitePragmas
+	}
+	dbx, err := openTraced(sqliteDriverName, dsn, semconv.DBSystemSqlite)
+	if err != nil {
+		return nil, translateError("connect", err)
+	}
+	if err := dbx.PingContext(ctx); err != nil {
+		dbx.Close()
+		return nil, translateError("connect", err)
+	}
+	pool.apply(dbx)
+	s := &SQLiteSession{
+		sqlAPIKeys:      sqlAPIKeys{dbx: dbx},
+		sqlTechnologies: sqlTechnologies{dbx: dbx},
+		Path:            path,
+		dbx:             dbx,
+	}
+	s.search.load = s.allCourses
+	return s, nil
+}
+
+func (s *SQLiteSession) Ping(ctx context.Context) error {
+	return translateError("ping", s.dbx.PingContext(ctx))
+}
+
+func (s *SQLiteSession) Close() error {
+	return s.dbx.Close()
+}
+
+func (s *SQLiteSession) Stats() sql.DBStats {
+	return s.dbx.Stats()
+}
+
+func (s *SQLiteSession) Migrator() (*migrations.Migrator, error) {
+	return migrations.New(s.dbx.DB, migrations.SQLite)
+}
+
+func (s *SQLiteSession) Create(ctx context.Context, params models.CreateCourseParams) (models.Course, error) {
+	c := models.CourseDatabase{
+		Id:    uuid.New().String(),
+		Name:  params.Name,
+		Price: params.Price,
+	}
+	technology := uniqueTechnologies(params.Technology)
+	tx, err := s.dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Course{}, translateError("create course", err)
+	}
+	defer tx.Rollback()
+	query := `INSERT INTO courses (id, name, price) VALUES (?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query, c.Id, c.Name, c.Price)
+	if err != nil {
+		if isSQLiteDuplicate(err) {
+			return models.Course{}, &DuplicateKeyError{Id: c.Id}
+		}
+		return models.Course{}, translateError("create course", err)
+	}
+	if err := saveTechnologies(ctx, tx, sqliteDialect, c.Id, technology); err != nil {
+		return models.Course{}, translateError("create course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Course{}, translateError("create course", err)
+	}
+	course := models.Course{
+		Id:         c.Id,
+		Name:       c.Name,
+		Price:      c.Price,
+		Technology: technology,
+		Version:    1,
+	}
+	s.search.put(course)
+	return course, nil
+}
+
+func (s *SQLiteSession) CreateMany(ctx context.Context, params []models.CreateCourseParams, atomic bool) ([]BulkResult, error) {
+	results, err := createMany(ctx, s.dbx, sqliteDialect, params, atomic)
+	for _, result := range results {
+		if result.Err == nil {
+			s.search.put(result.Course)
+		}
+	}
+	return results, err
+}
+
+func (s *SQLiteSession) Import(ctx context.Context, courses []models.Course, dryRun bool) (models.ImportSummary, error) {
+	summary, err := importCourses(ctx, s.dbx, sqliteDialect, courses, dryRun)
+	if err == nil && !dryRun {
+		for _, course := range courses {
+			s.search.put(course)
+		}
+	}
+	return summary, err
+}
+
+func (s *SQLiteSession) GetAll(ctx context.Context, params models.ListCoursesParams) (models.CoursePage, error) {
+	var rows []models.CourseDatabase
+	var total int
+	sort := models.SortWithTiebreak(params.Sort)
+
+	conds, args := listFilter(sqliteDialect, params)
+	err := s.dbx.GetContext(ctx, &total, `SELECT COUNT(*) FROM courses`+where(conds), args...)
+	if err != nil {
+		return models.CoursePage{}, translateError("count courses", err)
+	}
+	if params.Cursor != nil {
+		cond, cursorArgs := keysetCondition(sort, *params.Cursor)
+		conds = append(conds, cond)
+		args = append(args, cursorArgs...)
+	}
+	query := `SELECT id, name, price, version, deleted_at FROM courses` + where(conds) + orderBy(sort) + ` LIMIT ? OFFSET ?`
+	args = append(args, params.Limit+1, params.Offset)
+	if err := s.dbx.SelectContext(ctx, &rows, query, args...); err != nil {
+		return models.CoursePage{}, translateError("list courses", err)
+	}
+	hasMore := len(rows) > params.Limit
+	if hasMore {
+		rows = rows[:params.Limit]
+	}
+	courses, err := coursesFromRows(ctx, s.dbx, rows)
+	if err != nil {
+		return models.CoursePage{}, translateError("list courses", err)
+	}
+	page := models.CoursePage{Courses: courses, Total: total}
+	if hasMore {
+		page.NextCursor = models.NewCursor(params.Sort, page.Courses[len(page.Courses)-1]).Encode()
+	}
+	return page, nil
+}
+
+func (s *SQLiteSession) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (models.Course, error) {
+	var row models.CourseDatabase
+	query := `SELECT id, name, price, version, deleted_at FROM courses WHERE id = ?`
+	if !includeDeleted {
+		query += ` AND ` + notDeleted
+	}
+	if err := s.dbx.GetContext(ctx, &row, query, id.String()); err != nil {
+		return models.Course{}, translateError("get course", err)
+	}
+	courses, err := coursesFromRows(ctx, s.dbx, []models.CourseDatabase{row})
+	if err != nil {
+		return models.Course{}, translateError("get course", err)
+	}
+	return courses[0], nil
+}
+
+func (s *SQLiteSession) Update(ctx context.Context, id uuid.UUID, updateParams models.UpdateCourseParams, version int64) (models.Course, error) {
+	technology := uniqueTechnologies(updateParams.Technology)
+	tx, err := s.dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	defer tx.Rollback()
+	cond, condArgs := versionCondition(version)
+	query := `UPDATE courses SET name = ?, price = ?, version = version + 1 WHERE id = ? AND ` + notDeleted + cond + ` RETURNING version`
+	var newVersion int64
+	err = tx.GetContext(ctx, &newVersion, query, append([]any{updateParams.Name, updateParams.Price, id.String()}, condArgs...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Course{}, versionError(ctx, tx, "update course", id, false)
+	}
+	if err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	if err := saveTechnologies(ctx, tx, sqliteDialect, id.String(), technology); err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Course{}, translateError("update course", err)
+	}
+	course := models.Course{
+		Id:         id.String(),
+		Name:       updateParams.Name,
+		Price:      updateParams.Price,
+		Technology: technology,
+		Version:    newVersion,
+	}
+	s.search.put(course)
+	return course, nil
+}
+
+// Patch runs inside an immediate transaction, which takes the write lock up
+// front the same way SELECT ... FOR UPDATE does in mysql.
+func (s *SQLiteSession) Patch(ctx context.Context, id uuid.UUID, patch models.CoursePatch, version int64) (models.Course, error) {
+	tx, err := s.dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	defer tx.Rollback()
+
+	var row models.CourseDatabase
+	err = tx.GetContext(ctx, &row, `SELECT id, name, price, version FROM courses WHERE id = ? AND `+notDeleted, id.String())
+	if err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	if version != 0 && row.Version != version {
+		return models.Course{}, &Error{Op: "patch course", Kind: ErrVersionMismatch, Err: fmt.Errorf("course %s is at version %d", id, row.Version)}
+	}
+	courses, err := coursesFromRows(ctx, tx, []models.CourseDatabase{row})
+	if err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	patched, err := patch.Apply(courses[0])
+	if err != nil {
+		return models.Course{}, err
+	}
+	patched.Technology = uniqueTechnologies(patched.Technology)
+	if models.SameContent(patched, courses[0]) {
+		return courses[0], nil
+	}
+	patched.Version = row.Version + 1
+	query := `UPDATE courses SET name = ?, price = ?, version = version + 1 WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, patched.Name, patched.Price, id.String()); err != nil {
+		logging.FromContext(ctx).Error("patch course failed", "id", id, "err", err)
+		return models.Course{}, translateError("patch course", err)
+	}
+	if err := saveTechnologies(ctx, tx, sqliteDialect, id.String(), patched.Technology); err != nil {
+		logging.FromContext(ctx).Error("patch course failed", "id", id, "err", err)
+		return models.Course{}, translateError("patch course", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Course{}, translateError("patch course", err)
+	}
+	s.search.put(patched)
+	return patched, nil
+}
+
+// Delete marks the course deleted, see softDelete
+func (s *SQLiteSession) Delete(ctx context.Context, id uuid.UUID, version int64, at time.Time) error {
+	if err := softDelete(ctx, s.dbx, id, version, at); err != nil {
+		return err
+	}
+	s.search.remove(id.String())
+	return nil
+}
+
+func (s *SQLiteSession) Restore(ctx context.Context, id uuid.UUID, version int64) (models.Course, error) {
+	course, err := restore(ctx, s.dbx, id, version)
+	if err != nil {
+		return models.Course{}, err
+	}
+	s.search.put(course)
+	return course, nil
+}
+
+// Purge removes a deleted course, foreign_keys is on so its
+// course_technologies rows are deleted with it
+func (s *SQLiteSession) Purge(ctx context.Context, id uuid.UUID) error {
+	return purge(ctx, s.dbx, id)
+}
+
+// Search runs on the in-process index, built from every course on the first
+// search
+func (s *SQLiteSession) Search(ctx context.Context, params models.SearchParams) (models.SearchPage, error) {
+	index, err := s.search.get(ctx)
+	if err != nil {
+		return models.SearchPage{}, translateError("search courses", err)
+	}
+	page, err := searchCourses(ctx, s.dbx, index, params)
+	if err != nil {
+		return models.SearchPage{}, translateError("search courses", err)
+	}
+	return page, nil
+}
+
+// allCourses loads every course that is not deleted for the search index
+func (s *SQLiteSession) allCourses(ctx context.Context) ([]models.Course, error) {
+	var rows []models.CourseDatabase
+	if err := s.dbx.SelectContext(ctx, &rows, `SELECT id, name, price, version FROM courses WHERE `+notDeleted); err != nil {
+		return nil, err
+	}
+	return coursesFromRows(ctx, s.dbx, rows)
+}
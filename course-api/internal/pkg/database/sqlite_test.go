@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		url     string
+		backend string
+	}{
+		{"memory://", "memory"},
+		{"sqlite://" + filepath.Join(t.TempDir(), "courses.db"), "sqlite"},
+	}
+	for _, tt := range tests {
+		db, err := Open(ctx, tt.url, PoolConfig{})
+		if err != nil {
+			t.Errorf("Open(%q): %v", tt.url, err)
+			continue
+		}
+		if got := BackendName(db); got != tt.backend {
+			t.Errorf("Open(%q) opened %s, want %s", tt.url, got, tt.backend)
+		}
+		db.Close()
+	}
+	if _, err := Open(ctx, "sqlite://", PoolConfig{}); err == nil {
+		t.Error("Open(sqlite://) without a path did not fail")
+	}
+}
+
+// courses written to a sqlite file are there after reopening it, technologies
+// and versions included
+func TestSQLitePersists(t *testing.T) {
+	ctx := context.Background()
+	url := "sqlite://" + filepath.Join(t.TempDir(), "courses.db")
+	db, err := Open(ctx, url, PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := db.(*SQLiteSession).Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	course := mustCreate(t, db, "Go", 10, "Go", "gRPC")
+	course, err = db.Patch(ctx, parseTestID(t, course.Id), models.MergePatch(`{"name": "Go 2"}`), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(ctx, url, PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	got, err := db.GetByID(ctx, parseTestID(t, course.Id), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !models.SameContent(got, course) || got.Version != 2 {
+		t.Errorf("reopened %+v, want %+v", got, course)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+// backends returns an empty store for each backend that runs without a
+// server: the in-memory store and a migrated SQLite file
+func backends(t *testing.T) map[string]Interface {
+	t.Helper()
+	ctx := context.Background()
+	sqlite, err := NewSQLiteSession(ctx, filepath.Join(t.TempDir(), "test.db"), PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	migrator, err := sqlite.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Interface{"memory": NewMemoryStore(), "sqlite": sqlite}
+}
+
+func mustCreate(t *testing.T, db Interface, name string, price float64, technology ...string) models.Course {
+	t.Helper()
+	course, err := db.Create(context.Background(), models.CreateCourseParams{Name: name, Price: price, Technology: technology})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return course
+}
+
+func parseTestID(t *testing.T, id string) uuid.UUID {
+	t.Helper()
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
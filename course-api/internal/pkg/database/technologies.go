@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// sqlTechnologies implements ListTechnologies for both sql backends
+type sqlTechnologies struct {
+	dbx *sqlx.DB
+}
+
+// ListTechnologies returns every technology used by at least one course,
+// sorted by name
+func (s sqlTechnologies) ListTechnologies(ctx context.Context) ([]models.Technology, error) {
+	technologies := []models.Technology{}
+	query := `SELECT t.name, COUNT(*) AS course_count
+		FROM technologies AS t
+		JOIN course_technologies AS ct ON ct.technology_id = t.id
+		JOIN courses AS c ON c.id = ct.course_id
+		WHERE c.deleted_at IS NULL
+		GROUP BY t.id, t.name
+		ORDER BY t.name`
+	if err := s.dbx.SelectContext(ctx, &technologies, query); err != nil {
+		return nil, translateError("list technologies", err)
+	}
+	return technologies, nil
+}
+
+// uniqueTechnologies drops repeated names, keeping the first position. A
+// course is linked to a technology at most once.
+func uniqueTechnologies(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// saveTechnologies replaces the technologies of a course. names must already
+// be unique.
+func saveTechnologies(ctx context.Context, tx *sqlx.Tx, d dialect, courseID string, names []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM course_technologies WHERE course_id = ?`, courseID); err != nil {
+		return err
+	}
+	return linkTechnologies(ctx, tx, d, []models.Course{{Id: courseID, Technology: names}})
+}
+
+// linkTechnologies links every course to its technologies, creating the ones
+// that do not exist yet, with multi-row statements of at most
+// technologyBatchSize rows. The courses must not be linked yet and each one's
+// technologies must be unique.
+func linkTechnologies(ctx context.Context, tx *sqlx.Tx, d dialect, courses []models.Course) error {
+	var names []string
+	seen := map[string]bool{}
+	for _, course := range courses {
+		for _, name := range course.Technology {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	ids := make(map[string]int64, len(names))
+	for batch := range slices.Chunk(names, technologyBatchSize) {
+		args := make([]any, len(batch))
+		for i, name := range batch {
+			args[i] = name
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO technologies (name) VALUES `+placeholders(len(batch), 1)+` `+d.ignoreDuplicateTechnology, args...); err != nil {
+			return err
+		}
+		query, args, err := sqlx.In(`SELECT id, name FROM technologies WHERE name IN (?)`, batch)
+		if err != nil {
+			return err
+		}
+		var rows []struct {
+			Id   int64  `db:"id"`
+			Name string `db:"name"`
+		}
+		if err := tx.SelectContext(ctx, &rows, query, args...); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			ids[row.Name] = row.Id
+		}
+	}
+
+	var args []any
+	for _, course := range courses {
+		for position, name := range course.Technology {
+			args = append(args, course.Id, ids[name], position)
+		}
+	}
+	for batch := range slices.Chunk(args, 3*technologyBatchSize) {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO course_technologies (course_id, technology_id, position) VALUES `+placeholders(len(batch)/3, 3), batch...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeholders returns the VALUES list for rows rows of columns columns,
+// e.g. "(?, ?), (?, ?)"
+func placeholders(rows, columns int) string {
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", columns), ", ") + ")"
+	return strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ")
+}
+
+// loadTechnologies returns the technologies of each course in ids, in the
+// order they were saved
+func loadTechnologies(ctx context.Context, q sqlx.QueryerContext, ids []string) (map[string][]string, error) {
+	technologies := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return technologies, nil
+	}
+	query, args, err := sqlx.In(`SELECT ct.course_id, t.name
+		FROM course_technologies AS ct
+		JOIN technologies AS t ON t.id = ct.technology_id
+		WHERE ct.course_id IN (?)
+		ORDER BY ct.course_id, ct.position`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		CourseId string `db:"course_id"`
+		Name     string `db:"name"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		technologies[row.CourseId] = append(technologies[row.CourseId], row.Name)
+	}
+	return technologies, nil
+}
+
+// coursesFromRows joins the course rows with their technologies
+func coursesFromRows(ctx context.Context, q sqlx.QueryerContext, rows []models.CourseDatabase) ([]models.Course, error) {
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.Id
+	}
+	technologies, err := loadTechnologies(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+	courses := make([]models.Course, 0, len(rows))
+	for _, row := range rows {
+		technology := technologies[row.Id]
+		if technology == nil {
+			technology = []string{}
+		}
+		courses = append(courses, models.Course{
+			Id:         row.Id,
+			Name:       row.Name,
+			Price:      row.Price,
+			Technology: technology,
+			Version:    row.Version,
+			DeletedAt:  utc(row.DeletedAt),
+		})
+	}
+	return courses, nil
+}
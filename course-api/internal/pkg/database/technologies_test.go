@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestListTechnologies(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		technologies, err := db.ListTechnologies(ctx)
+		if err != nil || technologies == nil || len(technologies) != 0 {
+			t.Errorf("%s: empty store lists %v, %v, want an empty list", name, technologies, err)
+		}
+		mustCreate(t, db, "Go", 10, "Go", "gRPC")
+		mustCreate(t, db, "Rust", 20, "Rust", "gRPC")
+		course := mustCreate(t, db, "Old", 30, "Perl")
+		// technologies no longer used drop out of the list
+		if _, err := db.Update(ctx, parseTestID(t, course.Id), models.UpdateCourseParams{Name: "Old", Price: 30, Technology: []string{"Go"}}, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		technologies, err = db.ListTechnologies(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// sorted bytewise, mysql would sort by its collation instead
+		want := []models.Technology{{Name: "Go", CourseCount: 2}, {Name: "Rust", CourseCount: 1}, {Name: "gRPC", CourseCount: 2}}
+		if !slices.Equal(technologies, want) {
+			t.Errorf("%s: %v, want %v", name, technologies, want)
+		}
+	}
+}
+
+// technologies keep the order they were given in, updates included
+func TestTechnologyOrder(t *testing.T) {
+	ctx := context.Background()
+	for name, db := range backends(t) {
+		course := mustCreate(t, db, "Go", 10, "gRPC", "Go", "Docker")
+		id := parseTestID(t, course.Id)
+		updated, err := db.Update(ctx, id, models.UpdateCourseParams{Name: "Go", Price: 10, Technology: []string{"Docker", "gRPC", "Kubernetes"}}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := db.GetByID(ctx, id, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"Docker", "gRPC", "Kubernetes"}
+		if !slices.Equal(updated.Technology, want) || !slices.Equal(got.Technology, want) {
+			t.Errorf("%s: updated %v, read back %v, want %v", name, updated.Technology, got.Technology, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/XSAM/otelsql"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openTraced opens a pool through otelsql, so every statement run for a
+// traced request becomes a child span carrying db.statement. Statements run
+// outside of a trace (pool pings, migrations) create no spans.
+func openTraced(driverName, dsn string, system attribute.KeyValue) (*sqlx.DB, error) {
+	db, err := otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.NewDb(db, driverName), nil
+}
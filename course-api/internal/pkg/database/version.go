@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// versionCondition narrows a conditional write to the expected version, 0
+// writes whatever the version is
+func versionCondition(version int64) (string, []any) {
+	if version == 0 {
+		return "", nil
+	}
+	return ` AND version = ?`, []any{version}
+}
+
+// versionError explains why a write narrowed by versionCondition matched no
+// row: the course is gone, it is not in the deleted state the write expected
+// or someone else changed it first
+func versionError(ctx context.Context, q sqlx.QueryerContext, op string, id uuid.UUID, deleted bool) error {
+	var row models.CourseDatabase
+	err := sqlx.GetContext(ctx, q, &row, `SELECT version, deleted_at FROM courses WHERE id = ?`, id.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound(op, id)
+	}
+	if err != nil {
+		return translateError(op, err)
+	}
+	switch {
+	case row.DeletedAt != nil && !deleted:
+		return notFound(op, id)
+	case row.DeletedAt == nil && deleted:
+		return &Error{Op: op, Kind: ErrNotDeleted, Err: fmt.Errorf("course %s is not deleted", id)}
+	}
+	return &Error{Op: op, Kind: ErrVersionMismatch, Err: fmt.Errorf("course %s is at version %d", id, row.Version)}
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+// instrumentedDB times every call to the wrapped repository and counts its
+// failures
+type instrumentedDB struct {
+	db      database.Interface
+	backend string
+	m       *Metrics
+}
+
+// InstrumentDB wraps db so its operations show up in the db_operation_*
+// metrics. Pool stats are registered too when the backend has a pool.
+func (m *Metrics) InstrumentDB(db database.Interface) database.Interface {
+	backend := database.BackendName(db)
+	if pooled, ok := db.(database.Pooled); ok {
+		m.RegisterPool(backend, pooled.Stats)
+	}
+	return &instrumentedDB{db: db, backend: backend, m: m}
+}
+
+func (i *instrumentedDB) Unwrap() database.Interface {
+	return i.db
+}
+
+func (i *instrumentedDB) observe(op string, start time.Time, err error) {
+	i.m.dbDuration.WithLabelValues(i.backend, op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		i.m.dbErrors.WithLabelValues(i.backend, op, errorKind(err)).Inc()
+	}
+}
+
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, database.ErrConflict):
+		return "conflict"
+	case errors.Is(err, database.ErrInvalidData), errors.Is(err, models.ErrInvalidPatch), errors.As(err, new(models.ValidationError)):
+		return "invalid_data"
+	case errors.Is(err, database.ErrVersionMismatch):
+		return "version_mismatch"
+	case errors.Is(err, database.ErrNotDeleted):
+		return "not_deleted"
+	case errors.Is(err, database.ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "other"
+	}
+}
+
+func (i *instrumentedDB) Ping(ctx context.Context) (err error) {
+	defer func(start time.Time) { i.observe("ping", start, err) }(time.Now())
+	return i.db.Ping(ctx)
+}
+
+func (i *instrumentedDB) Close() error {
+	return i.db.Close()
+}
+
+func (i *instrumentedDB) GetAll(ctx context.Context, params models.ListCoursesParams) (page models.CoursePage, err error) {
+	defer func(start time.Time) { i.observe("get_all", start, err) }(time.Now())
+	return i.db.GetAll(ctx, params)
+}
+
+func (i *instrumentedDB) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (course models.Course, err error) {
+	defer func(start time.Time) { i.observe("get_by_id", start, err) }(time.Now())
+	return i.db.GetByID(ctx, id, includeDeleted)
+}
+
+func (i *instrumentedDB) Create(ctx context.Context, params models.CreateCourseParams) (course models.Course, err error) {
+	defer func(start time.Time) { i.observe("create", start, err) }(time.Now())
+	return i.db.Create(ctx, params)
+}
+
+func (i *instrumentedDB) CreateMany(ctx context.Context, params []models.CreateCourseParams, atomic bool) (results []database.BulkResult, err error) {
+	defer func(start time.Time) { i.observe("create_many", start, err) }(time.Now())
+	return i.db.CreateMany(ctx, params, atomic)
+}
+
+func (i *instrumentedDB) Import(ctx context.Context, courses []models.Course, dryRun bool) (summary models.ImportSummary, err error) {
+	defer func(start time.Time) { i.observe("import", start, err) }(time.Now())
+	return i.db.Import(ctx, courses, dryRun)
+}
+
+func (i *instrumentedDB) Update(ctx context.Context, id uuid.UUID, params models.UpdateCourseParams, version int64) (course models.Course, err error) {
+	defer func(start time.Time) { i.observe("update", start, err) }(time.Now())
+	return i.db.Update(ctx, id, params, version)
+}
+
+func (i *instrumentedDB) Patch(ctx context.Context, id uuid.UUID, patch models.CoursePatch, version int64) (course models.Course, err error) {
+	defer func(start time.Time) { i.observe("patch", start, err) }(time.Now())
+	return i.db.Patch(ctx, id, patch, version)
+}
+
+func (i *instrumentedDB) Delete(ctx context.Context, id uuid.UUID, version int64, at time.Time) (err error) {
+	defer func(start time.Time) { i.observe("delete", start, err) }(time.Now())
+	return i.db.Delete(ctx, id, version, at)
+}
+
+func (i *instrumentedDB) Restore(ctx context.Context, id uuid.UUID, version int64) (course models.Course, err error) {
+	defer func(start time.Time) { i.observe("restore", start, err) }(time.Now())
+	return i.db.Restore(ctx, id, version)
+}
+
+func (i *instrumentedDB) Purge(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(start time.Time) { i.observe("purge", start, err) }(time.Now())
+	return i.db.Purge(ctx, id)
+}
+
+func (i *instrumentedDB) Search(ctx context.Context, params models.SearchParams) (page models.SearchPage, err error) {
+	defer func(start time.Time) { i.observe("search", start, err) }(time.Now())
+	return i.db.Search(ctx, params)
+}
+
+func (i *instrumentedDB) ListTechnologies(ctx context.Context) (technologies []models.Technology, err error) {
+	defer func(start time.Time) { i.observe("list_technologies", start, err) }(time.Now())
+	return i.db.ListTechnologies(ctx)
+}
+
+func (i *instrumentedDB) CreateAPIKey(ctx context.Context, key models.APIKey, hash string) (err error) {
+	defer func(start time.Time) { i.observe("create_api_key", start, err) }(time.Now())
+	return i.db.CreateAPIKey(ctx, key, hash)
+}
+
+func (i *instrumentedDB) ListAPIKeys(ctx context.Context) (keys []models.APIKey, err error) {
+	defer func(start time.Time) { i.observe("list_api_keys", start, err) }(time.Now())
+	return i.db.ListAPIKeys(ctx)
+}
+
+func (i *instrumentedDB) GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	defer func(start time.Time) { i.observe("get_api_key", start, err) }(time.Now())
+	return i.db.GetAPIKeyByHash(ctx, hash)
+}
+
+func (i *instrumentedDB) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (key models.APIKey, err error) {
+	defer func(start time.Time) { i.observe("rotate_api_key", start, err) }(time.Now())
+	return i.db.RotateAPIKey(ctx, id, hash, prefix)
+}
+
+func (i *instrumentedDB) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (key models.APIKey, err error) {
+	defer func(start time.Time) { i.observe("revoke_api_key", start, err) }(time.Now())
+	return i.db.RevokeAPIKey(ctx, id, at)
+}
+
+func (i *instrumentedDB) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (err error) {
+	defer func(start time.Time) { i.observe("touch_api_key", start, err) }(time.Now())
+	return i.db.TouchAPIKey(ctx, id, at)
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestInstrumentDB(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	db := m.InstrumentDB(database.NewMemoryStore())
+	course, err := db.Create(ctx, models.CreateCourseParams{Name: "Go", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.GetByID(ctx, uuid.New(), false)
+	db.Update(ctx, uuid.MustParse(course.Id), models.UpdateCourseParams{Name: "Go", Price: 12}, 5)
+	db.Update(ctx, uuid.MustParse(course.Id), models.UpdateCourseParams{Name: "Go", Price: 12}, 1)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`course_api_db_operation_duration_seconds_count{backend="memory",operation="update"} 2`,
+		`course_api_db_operation_errors_total{backend="memory",kind="not_found",operation="get_by_id"} 1`,
+		`course_api_db_operation_errors_total{backend="memory",kind="version_mismatch",operation="update"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+	// the memory store has no pool
+	if strings.Contains(out, "course_api_db_pool_") {
+		t.Error("pool metrics registered for the memory store")
+	}
+}
+
+func TestPoolMetrics(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := database.NewSQLiteSession(ctx, filepath.Join(t.TempDir(), "test.db"), database.PoolConfig{MaxOpenConns: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	m := New()
+	m.InstrumentDB(sqlite)
+	if out := scrape(t, m); !strings.Contains(out, `course_api_db_pool_max_open_connections{backend="sqlite"} 4`) {
+		t.Errorf("pool stats missing:\n%s", out)
+	}
+}
@@ -0,0 +1,192 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestParseAppliedAt(t *testing.T) {
+	want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, s := range []string{
+		"2024-05-06 07:08:09",
+		// mysql with parseTime
+		"2024-05-06T07:08:09Z",
+		"2024-05-06T09:08:09+02:00",
+	} {
+		got, err := parseAppliedAt(s)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%q: got %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := parseAppliedAt("yesterday"); err == nil {
+		t.Error("an unreadable applied_at should be an error")
+	}
+}
+
+func TestLoadPairsFiles(t *testing.T) {
+	for _, dialect := range []string{MySQL, SQLite} {
+		migrations, err := load(dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Errorf("%s: migration %d has version %d, versions should have no gaps", dialect, i, m.Version)
+			}
+			if m.Up == "" || m.Down == "" {
+				t.Errorf("%s: migration %d_%s is missing its up or down file", dialect, m.Version, m.Name)
+			}
+		}
+	}
+	// both dialects move the schema through the same steps
+	mysql, _ := load(MySQL)
+	sqlite, _ := load(SQLite)
+	if len(mysql) != len(sqlite) {
+		t.Fatalf("%d mysql migrations, %d sqlite migrations", len(mysql), len(sqlite))
+	}
+	for i := range mysql {
+		if mysql[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d is %s in mysql, %s in sqlite", i+1, mysql[i].Name, sqlite[i].Name)
+		}
+	}
+}
+
+func TestUpStatusDown(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(openSQLite(t), SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().UTC().Add(-time.Second)
+	ran, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(m.migrations) {
+		t.Fatalf("ran %d of %d migrations", len(ran), len(m.migrations))
+	}
+	if ran, err := m.Up(ctx); err != nil || len(ran) != 0 {
+		t.Fatalf("second up ran %d migrations, err %v", len(ran), err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil || status.AppliedAt.Before(before) || status.AppliedAt.After(time.Now().Add(time.Second)) {
+			t.Errorf("migration %d applied at %v, want about now", status.Version, status.AppliedAt)
+		}
+	}
+
+	ran, err = m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 2 || ran[0].Version != len(m.migrations) {
+		t.Fatalf("down ran %+v, want the last two migrations newest first", ran)
+	}
+	statuses, err = m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		pending := status.Version > len(m.migrations)-2
+		if pending != (status.AppliedAt == nil) {
+			t.Errorf("migration %d applied at %v after rolling back two", status.Version, status.AppliedAt)
+		}
+	}
+
+	// every down file undoes its up file
+	if _, err := m.Down(ctx, len(m.migrations)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up after rolling everything back: %v", err)
+	}
+}
+
+func TestExecScriptSkipsComments(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	script := "-- a comment; with a semicolon\nCREATE TABLE a (id INTEGER);\n\n-- another\nCREATE TABLE b (\n    id INTEGER\n);\nINSERT INTO a VALUES (1)"
+	if err := execScript(ctx, db, script); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM a`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("got %d rows, err %v, the statement without a trailing ; should run too", n, err)
+	}
+}
+
+// 0003 moves the json technology column into the join table and back
+func TestTechnologiesBackfill(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m, err := New(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, len(m.migrations)-2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO courses (id, name, price, technology) VALUES
+		('a', 'Go', 10, '["Go", "gRPC", "Go"]'),
+		('b', 'Rust', 20, '["Rust", "gRPC"]'),
+		('c', 'None', 30, '[]')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	technologies := func(course string) string {
+		t.Helper()
+		var names sql.NullString
+		err := db.QueryRowContext(ctx, `SELECT group_concat(t.name, ',' ORDER BY ct.position)
+			FROM course_technologies AS ct JOIN technologies AS t ON t.id = ct.technology_id
+			WHERE ct.course_id = ?`, course).Scan(&names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names.String
+	}
+	for course, want := range map[string]string{"a": "Go,gRPC", "b": "Rust,gRPC", "c": ""} {
+		if got := technologies(course); got != want {
+			t.Errorf("course %s linked to %q, want %q", course, got, want)
+		}
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM technologies`).Scan(&count); err != nil || count != 3 {
+		t.Errorf("%d technologies, %v, want 3", count, err)
+	}
+
+	if _, err := m.Down(ctx, len(m.migrations)-2); err != nil {
+		t.Fatal(err)
+	}
+	var restored string
+	if err := db.QueryRowContext(ctx, `SELECT technology FROM courses WHERE id = 'a'`).Scan(&restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored != `["Go","gRPC"]` {
+		t.Errorf("rolled back to %s, want the linked technologies in order", restored)
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS courses (
+    id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    price DOUBLE NOT NULL,
+    technology JSON NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_courses_name (name),
+    INDEX idx_courses_price (price)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,14 @@
+-- only the sha256 of a key is stored, prefix is shown in listings
+CREATE TABLE IF NOT EXISTS api_keys (
+    id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes JSON NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    expires_at DATETIME(6) NULL,
+    last_used_at DATETIME(6) NULL,
+    revoked_at DATETIME(6) NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_api_keys_key_hash (key_hash)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
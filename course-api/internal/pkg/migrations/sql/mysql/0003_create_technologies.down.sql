@@ -0,0 +1,20 @@
+ALTER TABLE courses ADD COLUMN technology JSON NULL;
+
+-- JSON_ARRAYAGG takes no ORDER BY and an ordered derived table may be read in
+-- any order, GROUP_CONCAT is the aggregate that keeps the positions. Names
+-- are quoted one by one so the result is a valid json array.
+SET SESSION group_concat_max_len = 1048576;
+
+UPDATE courses SET technology = COALESCE((
+    SELECT CAST(CONCAT('[', GROUP_CONCAT(JSON_QUOTE(t.name) ORDER BY ct.position SEPARATOR ','), ']') AS JSON)
+    FROM course_technologies AS ct
+    JOIN technologies AS t ON t.id = ct.technology_id
+    WHERE ct.course_id = courses.id
+), JSON_ARRAY());
+
+SET SESSION group_concat_max_len = DEFAULT;
+
+ALTER TABLE courses MODIFY technology JSON NOT NULL;
+
+DROP TABLE IF EXISTS course_technologies;
+DROP TABLE IF EXISTS technologies;
@@ -0,0 +1,35 @@
+-- technologies move out of the json column into their own table so courses
+-- can be looked up by technology through an index. utf8mb4_bin keeps names
+-- case sensitive, the same as the old JSON_CONTAINS filter.
+CREATE TABLE IF NOT EXISTS technologies (
+    id INT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_technologies_name (name)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+-- position keeps the order the technologies were given in
+CREATE TABLE IF NOT EXISTS course_technologies (
+    course_id CHAR(36) NOT NULL,
+    technology_id INT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (course_id, technology_id),
+    INDEX idx_course_technologies_technology (technology_id, course_id),
+    CONSTRAINT fk_course_technologies_course FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE CASCADE,
+    CONSTRAINT fk_course_technologies_technology FOREIGN KEY (technology_id) REFERENCES technologies (id)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+INSERT IGNORE INTO technologies (name)
+SELECT DISTINCT jt.name
+FROM courses, JSON_TABLE(courses.technology, '$[*]' COLUMNS (name VARCHAR(255) PATH '$')) AS jt
+WHERE jt.name IS NOT NULL;
+
+-- a technology listed twice for one course keeps its first position
+INSERT INTO course_technologies (course_id, technology_id, position)
+SELECT c.id, t.id, MIN(jt.position) - 1
+FROM courses AS c
+JOIN JSON_TABLE(c.technology, '$[*]' COLUMNS (position FOR ORDINALITY, name VARCHAR(255) PATH '$')) AS jt
+JOIN technologies AS t ON t.name = jt.name
+GROUP BY c.id, t.id;
+
+ALTER TABLE courses DROP COLUMN technology;
@@ -0,0 +1,3 @@
+ALTER TABLE technologies DROP INDEX ft_technologies_search_name, DROP COLUMN search_name;
+
+ALTER TABLE courses DROP INDEX ft_courses_name;
@@ -0,0 +1,9 @@
+-- full-text indexes for GET /courses/search. technologies.name is case
+-- sensitive, so the index goes on a case insensitive copy of it.
+-- innodb_ft_min_token_size (3 by default) decides the shortest indexed word,
+-- the search falls back to a regular expression for shorter ones.
+ALTER TABLE courses ADD FULLTEXT INDEX ft_courses_name (name);
+
+ALTER TABLE technologies
+    ADD COLUMN search_name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci GENERATED ALWAYS AS (name) STORED,
+    ADD FULLTEXT INDEX ft_technologies_search_name (search_name);
@@ -0,0 +1,3 @@
+-- bumped by every write, served as the ETag of the course and checked
+-- against If-Match
+ALTER TABLE courses ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
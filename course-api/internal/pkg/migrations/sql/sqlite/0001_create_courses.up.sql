@@ -0,0 +1,11 @@
+-- name uses NOCASE so ordering and equality match the mysql collation
+CREATE TABLE IF NOT EXISTS courses (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL COLLATE NOCASE,
+    price REAL NOT NULL,
+    technology TEXT NOT NULL CHECK (json_valid(technology))
+);
+
+CREATE INDEX IF NOT EXISTS idx_courses_name ON courses (name);
+
+CREATE INDEX IF NOT EXISTS idx_courses_price ON courses (price);
@@ -0,0 +1,11 @@
+ALTER TABLE courses ADD COLUMN technology TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(technology));
+
+UPDATE courses SET technology = (
+    SELECT json_group_array(name ORDER BY position)
+    FROM course_technologies AS ct
+    JOIN technologies AS t ON t.id = ct.technology_id
+    WHERE ct.course_id = courses.id
+);
+
+DROP TABLE IF EXISTS course_technologies;
+DROP TABLE IF EXISTS technologies;
@@ -0,0 +1,32 @@
+-- technologies move out of the json column into their own table so courses
+-- can be looked up by technology through an index
+CREATE TABLE IF NOT EXISTS technologies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_technologies_name ON technologies (name);
+
+-- position keeps the order the technologies were given in
+CREATE TABLE IF NOT EXISTS course_technologies (
+    course_id TEXT NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    technology_id INTEGER NOT NULL REFERENCES technologies (id),
+    position INTEGER NOT NULL,
+    PRIMARY KEY (course_id, technology_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_course_technologies_technology ON course_technologies (technology_id, course_id);
+
+INSERT OR IGNORE INTO technologies (name)
+SELECT DISTINCT j.value
+FROM courses, json_each(courses.technology) AS j
+WHERE j.type = 'text';
+
+-- a technology listed twice for one course keeps its first position
+INSERT INTO course_technologies (course_id, technology_id, position)
+SELECT c.id, t.id, MIN(j.key)
+FROM courses AS c, json_each(c.technology) AS j
+JOIN technologies AS t ON t.name = j.value
+GROUP BY c.id, t.id;
+
+ALTER TABLE courses DROP COLUMN technology;
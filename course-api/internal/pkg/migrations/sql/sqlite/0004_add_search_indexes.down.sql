@@ -0,0 +1,2 @@
+-- sqlite searches with the in-process index of the search package, nothing
+-- to create. Kept so both dialects have the same versions.
@@ -0,0 +1,2 @@
+-- courses that were deleted but not purged become visible again
+ALTER TABLE courses DROP COLUMN deleted_at;
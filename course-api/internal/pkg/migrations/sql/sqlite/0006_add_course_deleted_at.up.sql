@@ -0,0 +1,2 @@
+-- set when a course is deleted, the row stays until an admin purges it
+ALTER TABLE courses ADD COLUMN deleted_at DATETIME;
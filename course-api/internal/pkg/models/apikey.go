@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// scopes an api key can carry, the same names as the jwt roles
+var APIKeyScopes = []string{"reader", "editor", "admin"}
+
+// APIKey is what the api shows about a key. The plaintext is never stored,
+// only Prefix (to recognise a key in listings) and a hash.
+type APIKey struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key may be used at now
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// APIKeyDatabase is an api_keys row, scopes are a json encoded array
+type APIKeyDatabase struct {
+	Id         string     `db:"id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     string     `db:"scopes"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+type CreateAPIKeyParams struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewAPIKey is returned when a key is created or rotated, the only time the
+// plaintext Key is shown
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// modes of POST /courses/bulk
+const (
+	// every course is created in one transaction, or none is
+	BulkAtomic = "atomic"
+	// valid courses are created even when others fail
+	BulkBestEffort = "best_effort"
+)
+
+// BulkItemResult is the outcome for the course at Index of the request. Status
+// is what POST /course would have answered for it.
+type BulkItemResult struct {
+	Index  int          `json:"index"`
+	Status int          `json:"status"`
+	Id     string       `json:"id,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type BulkResponse struct {
+	Mode    string           `json:"mode"`
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Results []BulkItemResult `json:"results"`
+}
+
+// ImportSummary is what POST /courses/import did, or with DryRun would have
+// done
+type ImportSummary struct {
+	DryRun  bool `json:"dry_run"`
+	Rows    int  `json:"rows"`
+	Created int  `json:"created"`
+	Updated int  `json:"updated"`
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"time"
 )
 
 // no space between json and fields
@@ -12,26 +13,43 @@ type Course struct {
 	Name       string   `json:"name"`
 	Price      float64  `json:"price"`
 	Technology []string `json:"technology"`
+	// starts at 1 and goes up with every change, the ETag of the course
+	Version int64 `json:"version"`
+	// set while the course is deleted, only admins get to see those
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-// during insertion of records the slice of strings need to be converted into string for saving
+// CourseDatabase is a courses row. Technologies live in their own table and
+// are joined in by the course id.
 type CourseDatabase struct {
-	Id         string  `json:"id"`
-	Name       string  `json:"name"`
-	Price      float64 `json:"price"`
-	Technology string  `json:"technology"`
+	Id      string  `json:"id"`
+	Name    string  `json:"name"`
+	Price   float64 `json:"price"`
+	Version int64   `json:"version"`
+	// NULL unless the course was deleted
+	DeletedAt *time.Time `json:"deleted_at" db:"deleted_at"`
+}
+
+// Technology is a technology with the number of courses using it
+type Technology struct {
+	Name        string `json:"name" db:"name"`
+	CourseCount int    `json:"course_count" db:"course_count"`
+}
+
+type TechnologyList struct {
+	Data []Technology `json:"data"`
 }
 
 type CreateCourseParams struct {
-	Name       string   `db:"name"`
-	Price      float64  `db:"price"`
-	Technology []string `db:"technology"`
+	Name       string   `json:"name" db:"name"`
+	Price      float64  `json:"price" db:"price"`
+	Technology []string `json:"technology" db:"technology"`
 }
 
 type UpdateCourseParams struct {
-	Name       string   `db:"name"`
-	Price      float64  `db:"price"`
-	Technology []string `db:"technology"`
+	Name       string   `json:"name" db:"name"`
+	Price      float64  `json:"price" db:"price"`
+	Technology []string `json:"technology" db:"technology"`
 }
 
 func (c *CreateCourseParams) IsEmpty() bool {
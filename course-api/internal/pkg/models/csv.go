@@ -0,0 +1,176 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CSVColumns is the header of the csv export. Imports take the columns in
+// any order and id can be left out.
+var CSVColumns = []string{"id", "name", "price", "technology"}
+
+// technologies share one csv cell, joined with this
+const TechnologySeparator = "|"
+
+// CSVRecord is the course as a csv row in CSVColumns order. Text cells that
+// a spreadsheet would run as a formula are escaped, see escapeFormula.
+func (c Course) CSVRecord() []string {
+	return []string{
+		c.Id,
+		escapeFormula(c.Name),
+		strconv.FormatFloat(c.Price, 'f', -1, 64),
+		escapeFormula(strings.Join(c.Technology, TechnologySeparator)),
+	}
+}
+
+// formulaPrefix is put in front of a cell starting with one of formulaStarts,
+// spreadsheets show it as text rather than evaluate it
+const formulaPrefix = "'"
+
+const formulaStarts = "=+-@\t\r"
+
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaStarts, rune(cell[0])) {
+		return formulaPrefix + cell
+	}
+	return cell
+}
+
+// unescapeFormula undoes escapeFormula, so an export imports unchanged
+func unescapeFormula(cell string) string {
+	if rest, ok := strings.CutPrefix(cell, formulaPrefix); ok && rest != "" && strings.ContainsRune(formulaStarts, rune(rest[0])) {
+		return rest
+	}
+	return cell
+}
+
+// CSVReader reads courses from csv that starts with a header row
+type CSVReader struct {
+	r *csv.Reader
+	// column index by name
+	columns map[string]int
+}
+
+// NewCSVReader reads and checks the header. Unknown, repeated or missing
+// columns come back as a ValidationError for line 1.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ValidationError{{Line: 1, Field: "header", Message: "file is empty"}}
+	}
+	if err != nil {
+		return nil, csvParseError(err)
+	}
+	columns := make(map[string]int, len(header))
+	var errs ValidationError
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case !slices.Contains(CSVColumns, name):
+			errs = append(errs, FieldError{Line: 1, Field: "header", Message: fmt.Sprintf("unknown column %q, expected %s", name, strings.Join(CSVColumns, ", "))})
+		case hasColumn(columns, name):
+			errs = append(errs, FieldError{Line: 1, Field: "header", Message: fmt.Sprintf("column %q appears more than once", name)})
+		default:
+			columns[name] = i
+		}
+	}
+	for _, name := range CSVColumns[1:] {
+		if !hasColumn(columns, name) {
+			errs = append(errs, FieldError{Line: 1, Field: "header", Message: fmt.Sprintf("column %q is missing", name)})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &CSVReader{r: cr, columns: columns}, nil
+}
+
+// Read returns the next course and the line it starts on, io.EOF after the
+// last one. Problems with the row come back as a ValidationError with the
+// line set, reading can go on after them. Any other error is fatal.
+func (c *CSVReader) Read() (Course, int, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Course{}, 0, err
+		}
+		return Course{}, 0, csvParseError(err)
+	}
+	line, _ := c.r.FieldPos(0)
+	if len(record) != len(c.columns) {
+		return Course{}, line, ValidationError{{Line: line, Field: "row", Message: fmt.Sprintf("has %d fields, the header has %d", len(record), len(c.columns))}}
+	}
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok {
+			return strings.TrimSpace(unescapeFormula(record[i]))
+		}
+		return ""
+	}
+
+	var errs ValidationError
+	course := Course{Id: field("id"), Name: field("name")}
+	if course.Id != "" {
+		if _, err := uuid.Parse(course.Id); err != nil {
+			errs = append(errs, FieldError{Field: "id", Message: "must be a uuid"})
+		}
+	}
+	if val := field("price"); val != "" {
+		if course.Price, err = strconv.ParseFloat(val, 64); err != nil {
+			errs = append(errs, FieldError{Field: "price", Message: "must be a number"})
+		}
+	}
+	if val := field("technology"); val != "" {
+		for _, name := range strings.Split(val, TechnologySeparator) {
+			course.Technology = append(course.Technology, strings.TrimSpace(name))
+		}
+	}
+	if err := validateCourse(course.Name, course.Price, course.Technology); err != nil {
+		errs = append(errs, err.(ValidationError)...)
+	}
+	if len(errs) > 0 {
+		// a bad price is reported once, not again by validateCourse
+		errs = dedupeFieldErrors(errs)
+		for i := range errs {
+			errs[i].Line = line
+		}
+		return Course{}, line, errs
+	}
+	return course, line, nil
+}
+
+func hasColumn(columns map[string]int, name string) bool {
+	_, ok := columns[name]
+	return ok
+}
+
+func dedupeFieldErrors(errs ValidationError) ValidationError {
+	seen := map[string]bool{}
+	out := errs[:0]
+	for _, e := range errs {
+		if !seen[e.Field] {
+			seen[e.Field] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// csvParseError turns malformed csv, e.g. a stray quote, into a
+// ValidationError for the line it was found on
+func csvParseError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return ValidationError{{Line: parseErr.Line, Field: "row", Message: parseErr.Err.Error()}}
+	}
+	return err
+}
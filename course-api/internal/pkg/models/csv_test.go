@@ -0,0 +1,111 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCSVRecordEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		name, technology         string
+		wantName, wantTechnology string
+	}{
+		{"Go", "Go", "Go", "Go"},
+		{"=HYPERLINK(\"http://evil\")", "Go", "'=HYPERLINK(\"http://evil\")", "Go"},
+		{"+1", "@SUM(A1)", "'+1", "'@SUM(A1)"},
+		{"-2", "\tGo", "'-2", "'\tGo"},
+		{"\rGo", "Go", "'\rGo", "Go"},
+		// only the start of a cell counts
+		{"C=1", "Go", "C=1", "Go"},
+		{"'quoted", "Go", "'quoted", "Go"},
+	}
+	for _, tt := range tests {
+		record := Course{Name: tt.name, Price: 1, Technology: []string{tt.technology}}.CSVRecord()
+		if record[1] != tt.wantName || record[3] != tt.wantTechnology {
+			t.Errorf("%q, %q: exported as %q, %q, want %q, %q", tt.name, tt.technology, record[1], record[3], tt.wantName, tt.wantTechnology)
+		}
+	}
+}
+
+// an export imports unchanged, escaped cells included
+func TestCSVRoundTrip(t *testing.T) {
+	courses := []Course{
+		{Id: "7b0e7d2e-3c6e-4a4b-9a53-1c1f4f6b2a10", Name: "=1+1", Price: 9.5, Technology: []string{"-Go", "Rust"}},
+		{Id: "2c1f1c33-6d5e-4f0a-8d5b-6a7e2b9c3d21", Name: "Learn, \"quoted\" Go", Price: 100, Technology: []string{"Go"}},
+		{Id: "a3f9e1d4-0b2c-4e7a-9c8d-5f6a7b8c9d02", Name: "'kept", Price: 1, Technology: []string{"@home"}},
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(CSVColumns)
+	for _, course := range courses {
+		w.Write(course.CSVRecord())
+	}
+	w.Flush()
+
+	r, err := NewCSVReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range courses {
+		got, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Id != want.Id || got.Name != want.Name || got.Price != want.Price || !slices.Equal(got.Technology, want.Technology) {
+			t.Errorf("read back %+v, want %+v", got, want)
+		}
+	}
+	if _, _, err := r.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v after the last row, want io.EOF", err)
+	}
+}
+
+func TestCSVReaderErrors(t *testing.T) {
+	tests := []struct {
+		name, csv string
+		field     string
+		line      int
+	}{
+		{"empty", "", "header", 1},
+		{"unknown column", "name,price,technology,author\n", "header", 1},
+		{"missing column", "name,price\n", "header", 1},
+		{"repeated column", "name,name,price,technology\n", "header", 1},
+		{"bad price", "name,price,technology\nGo,cheap,Go\n", "price", 2},
+		{"bad id", "id,name,price,technology\n42,Go,10,Go\n", "id", 2},
+		{"short row", "name,price,technology\nGo,10\n", "row", 2},
+		{"stray quote", "name,price,technology\nGo,10,Go\n\"Rust,10,Rust\n", "row", 3},
+	}
+	for _, tt := range tests {
+		r, err := NewCSVReader(strings.NewReader(tt.csv))
+		if err == nil {
+			_, _, err = r.Read()
+			if tt.name == "stray quote" && err == nil {
+				_, _, err = r.Read()
+			}
+		}
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: got %v, want a ValidationError", tt.name, err)
+			continue
+		}
+		if validationErr[0].Field != tt.field || validationErr[0].Line != tt.line {
+			t.Errorf("%s: got %+v, want field %s on line %d", tt.name, validationErr[0], tt.field, tt.line)
+		}
+	}
+}
+
+func TestValidateRejectsSeparatorInTechnology(t *testing.T) {
+	err := validateCourse("Go", 10, []string{"Go", "C|C++"})
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) || validationErr[0].Field != "technology" {
+		t.Errorf("got %v, want a technology error", err)
+	}
+	if err := validateCourse("Go | Rust", 10, []string{"Go"}); err != nil {
+		t.Errorf("got %v, the separator is fine in names", err)
+	}
+}
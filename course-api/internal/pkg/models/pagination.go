@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// columns a client is allowed to sort the course list on
+var SortableFields = map[string]bool{
+	"id":    true,
+	"name":  true,
+	"price": true,
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListCoursesParams holds everything GET /courses can be asked for.
+// Cursor and Offset are mutually exclusive.
+type ListCoursesParams struct {
+	Limit      int
+	Offset     int
+	Cursor     *Cursor
+	Technology string
+	NamePrefix string
+	MinPrice   *float64
+	MaxPrice   *float64
+	Sort       []SortField
+	// list deleted courses along with the others
+	IncludeDeleted bool
+}
+
+// CoursePage is one page of courses as returned by the database layer
+type CoursePage struct {
+	Courses    []Course
+	Total      int
+	NextCursor string
+}
+
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type CourseList struct {
+	Data       []Course   `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// Cursor marks the last row of a page. It carries the values of every sortable
+// column so the next page can continue right after it (keyset pagination).
+type Cursor struct {
+	Sort  string  `json:"s"`
+	Id    string  `json:"id"`
+	Name  string  `json:"n"`
+	Price float64 `json:"p"`
+}
+
+func NewCursor(sort []SortField, c Course) Cursor {
+	return Cursor{
+		Sort:  FormatSort(sort),
+		Id:    c.Id,
+		Name:  c.Name,
+		Price: c.Price,
+	}
+}
+
+// Value returns the cursor value for the given sortable column
+func (c Cursor) Value(field string) any {
+	switch field {
+	case "name":
+		return c.Name
+	case "price":
+		return c.Price
+	default:
+		return c.Id
+	}
+}
+
+// Encode makes the cursor opaque for clients
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(val string) (Cursor, error) {
+	var c Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(raw, &c); err != nil || c.Id == "" {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// ParseSort reads a sort expression like "price,-name".
+// A leading "-" means descending.
+func ParseSort(val string) ([]SortField, error) {
+	var fields []SortField
+	for _, part := range strings.Split(val, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		f := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !SortableFields[f.Field] {
+			return nil, errors.New("cannot sort on field " + f.Field)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func FormatSort(fields []SortField) string {
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f.Desc {
+			parts = append(parts, "-"+f.Field)
+		} else {
+			parts = append(parts, f.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// SortWithTiebreak appends id to the sort so every row has a unique position,
+// which keyset pagination needs.
+func SortWithTiebreak(fields []SortField) []SortField {
+	for _, f := range fields {
+		if f.Field == "id" {
+			return fields
+		}
+	}
+	out := make([]SortField, len(fields), len(fields)+1)
+	copy(out, fields)
+	return append(out, SortField{Field: "id"})
+}
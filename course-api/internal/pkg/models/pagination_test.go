@@ -0,0 +1,60 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		val  string
+		want []SortField
+		ok   bool
+	}{
+		{"price", []SortField{{Field: "price"}}, true},
+		{"price,-name", []SortField{{Field: "price"}, {Field: "name", Desc: true}}, true},
+		{" -id , name ,", []SortField{{Field: "id", Desc: true}, {Field: "name"}}, true},
+		{"", nil, true},
+		{"technology", nil, false},
+		{"price,--name", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSort(tt.val)
+		if (err == nil) != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("ParseSort(%q) = %v, %v, want %v", tt.val, got, err, tt.want)
+		}
+		if tt.ok && tt.val != "" {
+			if again, _ := ParseSort(FormatSort(got)); !slices.Equal(again, got) {
+				t.Errorf("FormatSort(%v) = %q does not parse back", got, FormatSort(got))
+			}
+		}
+	}
+}
+
+func TestSortWithTiebreak(t *testing.T) {
+	got := SortWithTiebreak([]SortField{{Field: "price", Desc: true}})
+	if want := []SortField{{Field: "price", Desc: true}, {Field: "id"}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	withID := []SortField{{Field: "id", Desc: true}, {Field: "name"}}
+	if got := SortWithTiebreak(withID); !slices.Equal(got, withID) {
+		t.Errorf("got %v, a sort with id is unique already", got)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	course := Course{Id: "7b0e7d2e-3c6e-4a4b-9a53-1c1f4f6b2a10", Name: "Go, \"quoted\"", Price: 9.99}
+	cursor := NewCursor([]SortField{{Field: "price", Desc: true}}, course)
+	got, err := DecodeCursor(cursor.Encode())
+	if err != nil || got != cursor {
+		t.Fatalf("got %+v, %v, want %+v", got, err, cursor)
+	}
+	if got.Sort != "-price" || got.Value("price") != 9.99 || got.Value("name") != course.Name || got.Value("id") != course.Id {
+		t.Errorf("cursor %+v", got)
+	}
+	for _, bad := range []string{"", "not base64!", "bm90IGpzb24", "e30"} {
+		if _, err := DecodeCursor(bad); err == nil {
+			t.Errorf("DecodeCursor(%q) succeeded", bad)
+		}
+	}
+}
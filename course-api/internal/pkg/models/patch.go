@@ -0,0 +1,86 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// ErrInvalidPatch is returned when a patch cannot be applied or leaves the
+// course in an invalid state
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// CoursePatch changes some fields of a course. Apply must not modify the id,
+// the version or deleted_at.
+type CoursePatch interface {
+	Apply(course Course) (Course, error)
+}
+
+// MergePatch is a JSON Merge Patch document (RFC 7396)
+type MergePatch []byte
+
+// JSONPatch is a JSON Patch document (RFC 6902)
+type JSONPatch struct {
+	ops jsonpatch.Patch
+}
+
+func DecodeJSONPatch(doc []byte) (JSONPatch, error) {
+	ops, err := jsonpatch.DecodePatch(doc)
+	if err != nil {
+		return JSONPatch{}, err
+	}
+	return JSONPatch{ops: ops}, nil
+}
+
+// SameContent reports whether a and b only differ in their version
+func SameContent(a, b Course) bool {
+	return a.Id == b.Id && a.Name == b.Name && a.Price == b.Price && slices.Equal(a.Technology, b.Technology)
+}
+
+func (p MergePatch) Apply(course Course) (Course, error) {
+	return applyPatch(course, func(doc []byte) ([]byte, error) {
+		return jsonpatch.MergePatch(doc, p)
+	})
+}
+
+func (p JSONPatch) Apply(course Course) (Course, error) {
+	return applyPatch(course, p.ops.Apply)
+}
+
+// applyPatch runs the patch over the json form of the course and reads the
+// result back, rejecting unknown fields and id, version or deleted_at
+// changes. The result is validated like a PUT body, failures come back as a
+// ValidationError.
+func applyPatch(course Course, patch func([]byte) ([]byte, error)) (Course, error) {
+	doc, err := json.Marshal(course)
+	if err != nil {
+		return Course{}, err
+	}
+	patched, err := patch(doc)
+	if err != nil {
+		return Course{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	var result Course
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return Course{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if result.Id != course.Id {
+		return Course{}, fmt.Errorf("%w: id cannot be changed", ErrInvalidPatch)
+	}
+	if result.Version != course.Version {
+		return Course{}, fmt.Errorf("%w: version cannot be changed, send If-Match instead", ErrInvalidPatch)
+	}
+	if result.DeletedAt != nil {
+		return Course{}, fmt.Errorf("%w: deleted_at cannot be changed, use DELETE or restore instead", ErrInvalidPatch)
+	}
+	if err := validateCourse(result.Name, result.Price, result.Technology); err != nil {
+		return Course{}, err
+	}
+	return result, nil
+}
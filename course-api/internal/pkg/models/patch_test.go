@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func testCourse() Course {
+	return Course{Id: "c1", Name: "Go basics", Price: 10, Technology: []string{"go"}, Version: 3}
+}
+
+func TestMergePatch(t *testing.T) {
+	course, err := MergePatch(`{"price": 12, "technology": ["go", "docker"]}`).Apply(testCourse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if course.Name != "Go basics" || course.Price != 12 || !slices.Equal(course.Technology, []string{"go", "docker"}) {
+		t.Errorf("got %+v", course)
+	}
+	if course.Version != 3 {
+		t.Errorf("version = %d, the patch must leave it to the backend", course.Version)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	patch, err := DecodeJSONPatch([]byte(`[{"op": "add", "path": "/technology/-", "value": "gin"}, {"op": "replace", "path": "/name", "value": "Gin"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	course, err := patch.Apply(testCourse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if course.Name != "Gin" || !slices.Equal(course.Technology, []string{"go", "gin"}) {
+		t.Errorf("got %+v", course)
+	}
+}
+
+// invalid results fail like a PUT body would, with the fields at fault
+func TestPatchValidatesResult(t *testing.T) {
+	tests := []struct {
+		patch string
+		field string
+	}{
+		{`{"price": -5}`, "price"},
+		{`{"price": 0}`, "price"},
+		{`{"name": "  "}`, "name"},
+		{`{"technology": []}`, "technology"},
+		{`{"technology": [""]}`, "technology"},
+		{`{"technology": null}`, "technology"},
+	}
+	for _, tt := range tests {
+		_, err := MergePatch(tt.patch).Apply(testCourse())
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: got %v, want a ValidationError", tt.patch, err)
+			continue
+		}
+		if len(validationErr) != 1 || validationErr[0].Field != tt.field {
+			t.Errorf("%s: got %v, want an error on %s", tt.patch, validationErr, tt.field)
+		}
+	}
+}
+
+func TestPatchRejectsReadOnlyFields(t *testing.T) {
+	for _, patch := range []string{
+		`{"id": "c2"}`,
+		`{"version": 4}`,
+		`{"deleted_at": "2024-01-01T00:00:00Z"}`,
+		`{"unknown": 1}`,
+		`[1, 2]`,
+	} {
+		if _, err := MergePatch(patch).Apply(testCourse()); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%s: got %v, want ErrInvalidPatch", patch, err)
+		}
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	a := testCourse()
+	b := testCourse()
+	b.Version++
+	if !SameContent(a, b) {
+		t.Error("courses differing only in version should have the same content")
+	}
+	b.Technology = []string{"go", "docker"}
+	if SameContent(a, b) {
+		t.Error("courses with different technologies should differ")
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+// at most this many distinct terms are searched for
+const MaxSearchTerms = 10
+
+// SearchParams is what GET /courses/search can be asked for. Terms are the
+// distinct lowercase words of the q parameter.
+type SearchParams struct {
+	Terms  []string
+	Limit  int
+	Offset int
+}
+
+// SearchHit is a matching course with its relevance, higher is better.
+// Scores are only comparable within one search.
+type SearchHit struct {
+	Course     Course      `json:"course"`
+	Score      float64     `json:"score"`
+	Highlights *Highlights `json:"highlights,omitempty"`
+}
+
+// Highlights holds the fields that matched, with the matching words wrapped
+// in <mark> tags and the rest html escaped
+type Highlights struct {
+	Name       string   `json:"name,omitempty"`
+	Technology []string `json:"technology,omitempty"`
+}
+
+// SearchPage is one page of hits as returned by the database layer
+type SearchPage struct {
+	Hits  []SearchHit
+	Total int
+}
+
+type SearchResults struct {
+	Data       []SearchHit `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// csv line the error was found on, only set by imports
+	Line int `json:"line,omitempty"`
+}
+
+// ValidationError lists every field that failed validation
+type ValidationError []FieldError
+
+func (v ValidationError) Error() string {
+	parts := make([]string, 0, len(v))
+	for _, e := range v {
+		parts = append(parts, e.Field+": "+e.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func NewFieldError(field, message string) ValidationError {
+	return ValidationError{{Field: field, Message: message}}
+}
+
+func (c *CreateCourseParams) Validate() error {
+	return validateCourse(c.Name, c.Price, c.Technology)
+}
+
+func (c *UpdateCourseParams) Validate() error {
+	return validateCourse(c.Name, c.Price, c.Technology)
+}
+
+func validateCourse(name string, price float64, technology []string) error {
+	var errs ValidationError
+	if strings.TrimSpace(name) == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "is required"})
+	}
+	if price <= 0 {
+		errs = append(errs, FieldError{Field: "price", Message: "must be greater than 0"})
+	}
+	if len(technology) == 0 {
+		errs = append(errs, FieldError{Field: "technology", Message: "must contain at least one entry"})
+	}
+	for _, t := range technology {
+		if strings.TrimSpace(t) == "" {
+			errs = append(errs, FieldError{Field: "technology", Message: "cannot contain empty values"})
+			break
+		}
+	}
+	for _, t := range technology {
+		// the csv export joins technologies with it
+		if strings.Contains(t, TechnologySeparator) {
+			errs = append(errs, FieldError{Field: "technology", Message: "cannot contain " + TechnologySeparator})
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *CreateAPIKeyParams) Validate(now time.Time) error {
+	var errs ValidationError
+	if strings.TrimSpace(p.Name) == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "is required"})
+	}
+	if len(p.Scopes) == 0 {
+		errs = append(errs, FieldError{Field: "scopes", Message: "needs at least one scope"})
+	}
+	for _, scope := range p.Scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			errs = append(errs, FieldError{Field: "scopes", Message: "unknown scope " + scope + ", expected reader, editor or admin"})
+		}
+	}
+	if p.ExpiresAt != nil && !p.ExpiresAt.After(now) {
+		errs = append(errs, FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
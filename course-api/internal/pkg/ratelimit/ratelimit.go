@@ -0,0 +1,128 @@
+// Package ratelimit keeps one token bucket per client key. Buckets refill at
+// a steady rate up to a burst size; a bucket that has been idle long enough
+// to refill completely is dropped, so memory only grows with active clients.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Limit struct {
+	// tokens added per second
+	Rate float64
+	// bucket size, the most requests allowed back to back
+	Burst int
+}
+
+// Result describes the bucket after a request, in the terms of the
+// RateLimit-* and Retry-After headers
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// until the bucket is full again
+	Reset time.Duration
+	// until the next request is allowed, zero when Allowed
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type Limiter struct {
+	limit Limit
+	// buckets unused for this long are evicted
+	idleTimeout time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns a limiter. idleTimeout is raised to the time a bucket needs to
+// refill, evicting earlier would hand a client a fresh burst.
+func New(limit Limit, idleTimeout time.Duration) *Limiter {
+	refill := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+	return &Limiter{
+		limit:       limit,
+		idleTimeout: max(idleTimeout, refill),
+		buckets:     make(map[string]*bucket),
+		now:         time.Now,
+	}
+}
+
+// Allow takes a token from key's bucket if there is one
+func (l *Limiter) Allow(key string) Result {
+	return l.take(key, true)
+}
+
+// Peek reports whether Allow would let a request through, without taking a
+// token
+func (l *Limiter) Peek(key string) Result {
+	return l.take(key, false)
+}
+
+func (l *Limiter) take(key string, consume bool) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	tokens := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if ok {
+		elapsed := now.Sub(b.last).Seconds()
+		tokens = math.Min(tokens, b.tokens+elapsed*l.limit.Rate)
+	}
+
+	result := Result{Limit: l.limit.Burst}
+	if tokens >= 1 {
+		if consume {
+			tokens--
+		}
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - tokens)
+	}
+	if consume {
+		if !ok {
+			b = &bucket{}
+			l.buckets[key] = b
+		}
+		b.tokens, b.last = tokens, now
+	}
+	result.Remaining = int(tokens)
+	result.Reset = l.duration(float64(l.limit.Burst) - tokens)
+	return result
+}
+
+// Len is the number of buckets currently kept
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep drops idle buckets, at most once per idle timeout so the cost is
+// spread over many requests
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration is how long it takes to refill tokens
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a settable time source for a limiter
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(limit Limit, idleTimeout time.Duration) (*Limiter, *clock) {
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New(limit, idleTimeout)
+	l.now = c.now
+	return l, c
+}
+
+func TestAllow(t *testing.T) {
+	// one token every 2s, 3 back to back
+	l, c := newTestLimiter(Limit{Rate: 0.5, Burst: 3}, 0)
+	steps := []struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{0, true, 2, 2 * time.Second, 0},
+		{0, true, 1, 4 * time.Second, 0},
+		{0, true, 0, 6 * time.Second, 0},
+		{0, false, 0, 6 * time.Second, 2 * time.Second},
+		{time.Second, false, 0, 5 * time.Second, time.Second},
+		// a quarter token is left over after this one
+		{1500 * time.Millisecond, true, 0, 5500 * time.Millisecond, 0},
+		{time.Second, false, 0, 4500 * time.Millisecond, 500 * time.Millisecond},
+		// a long pause refills up to the burst, not beyond
+		{time.Hour, true, 2, 2 * time.Second, 0},
+	}
+	for i, step := range steps {
+		c.advance(step.advance)
+		got := l.Allow("client")
+		want := Result{Allowed: step.allowed, Limit: 3, Remaining: step.remaining, Reset: step.reset, RetryAfter: step.retryAfter}
+		if got != want {
+			t.Errorf("step %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestBucketsPerKey(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Rate: 1, Burst: 1}, 0)
+	if !l.Allow("a").Allowed || l.Allow("a").Allowed {
+		t.Fatal("a should get exactly one request")
+	}
+	if !l.Allow("b").Allowed {
+		t.Error("b is limited by a's bucket")
+	}
+}
+
+func TestIdleBucketsAreEvicted(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 1, Burst: 10}, time.Minute)
+	l.Allow("a")
+	l.Allow("b")
+	c.advance(30 * time.Second)
+	l.Allow("b")
+	if l.Len() != 2 {
+		t.Fatalf("%d buckets, want 2", l.Len())
+	}
+	c.advance(40 * time.Second)
+	// the sweep drops a, idle for 70s, and keeps b, idle for 40s
+	l.Allow("c")
+	if l.Len() != 2 {
+		t.Errorf("%d buckets after a minute, want b and c", l.Len())
+	}
+	c.advance(2 * time.Minute)
+	l.Allow("c")
+	if l.Len() != 1 {
+		t.Errorf("%d buckets, want only c", l.Len())
+	}
+}
+
+// a bucket is kept at least until it has refilled, evicting it earlier would
+// let a client reset its limit by pausing
+func TestIdleTimeoutCoversRefill(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 0.1, Burst: 2}, time.Second)
+	l.Allow("a")
+	l.Allow("a")
+	c.advance(5 * time.Second)
+	if got := l.Allow("a"); got.Allowed {
+		t.Errorf("got %+v, the bucket was evicted before it refilled", got)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	l, c := newTestLimiter(Limit{Rate: 1, Burst: 1}, 0)
+	if r := l.Peek("a"); !r.Allowed || r.Remaining != 1 || l.Len() != 0 {
+		t.Errorf("peek at a new key: %+v with %d buckets, want allowed and no bucket created", r, l.Len())
+	}
+	l.Allow("a")
+	if r := l.Peek("a"); r.Allowed || r.RetryAfter != time.Second {
+		t.Errorf("peek at an empty bucket: %+v, want refused with a 1s retry", r)
+	}
+	c.advance(time.Second)
+	if r := l.Peek("a"); !r.Allowed {
+		t.Errorf("peek after a refill: %+v, want allowed", r)
+	}
+	if r := l.Allow("a"); !r.Allowed {
+		t.Errorf("peeking took the token: %+v", r)
+	}
+}
@@ -0,0 +1,197 @@
+// Package search is the in-process full-text index for the backends without
+// one of their own. Course names and technologies are split into lowercase
+// terms; a query matches every course that has any of its terms, ranked by
+// how many of them it has and how rare they are.
+package search
+
+import (
+	"cmp"
+	"html"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// weights of a term occurrence by field, a match in the name counts more than
+// one in the technologies. The mysql search uses the same weights.
+const (
+	NameWeight       = 2
+	TechnologyWeight = 1
+)
+
+type Document struct {
+	ID         string
+	Name       string
+	Technology []string
+}
+
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+type Index struct {
+	mu sync.RWMutex
+	// term -> document id -> weighted occurrences
+	postings map[string]map[string]float64
+	docs     map[string]indexedDoc
+}
+
+type indexedDoc struct {
+	// lowercased, to order equal scores the way the sql backends do
+	name  string
+	terms []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string]indexedDoc),
+	}
+}
+
+// Put adds doc or replaces the document with the same id
+func (ix *Index) Put(doc Document) {
+	weights := map[string]float64{}
+	for _, term := range tokens(doc.Name) {
+		weights[term] += NameWeight
+	}
+	for _, technology := range doc.Technology {
+		for _, term := range tokens(technology) {
+			weights[term] += TechnologyWeight
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(doc.ID)
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		postings, ok := ix.postings[term]
+		if !ok {
+			postings = make(map[string]float64)
+			ix.postings[term] = postings
+		}
+		postings[doc.ID] = weight
+		terms = append(terms, term)
+	}
+	ix.docs[doc.ID] = indexedDoc{name: strings.ToLower(doc.Name), terms: terms}
+}
+
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+// Search returns every document matching at least one of terms, best first.
+// Each matched term adds its weight in the document times its inverse
+// document frequency (the BM25 idf), so rare terms and documents matching
+// several terms rank higher. Equal scores are ordered by name, then id.
+func (ix *Index) Search(terms []string) []Hit {
+	ix.mu.RLock()
+	n := float64(len(ix.docs))
+	scores := map[string]float64{}
+	for _, term := range terms {
+		postings := ix.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, weight := range postings {
+			scores[id] += idf * weight
+		}
+	}
+	hits := make([]Hit, 0, len(scores))
+	names := make(map[string]string, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+		names[id] = ix.docs[id].name
+	}
+	ix.mu.RUnlock()
+
+	slices.SortFunc(hits, func(a, b Hit) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(names[a.ID], names[b.ID]), strings.Compare(a.ID, b.ID))
+	})
+	return hits
+}
+
+// Terms splits a query into its distinct lowercase terms
+func Terms(query string) []string {
+	var terms []string
+	for _, term := range tokens(query) {
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// tokens are the runs of letters and digits in s, lowercased
+func tokens(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return !isTermRune(r)
+	})
+	for i, field := range fields {
+		fields[i] = strings.ToLower(field)
+	}
+	return fields
+}
+
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Highlight wraps the words of text that are one of terms in <mark> tags and
+// html escapes the rest, so the result can be shown as html. It reports
+// whether anything was marked.
+func Highlight(text string, terms []string) (string, bool) {
+	var b strings.Builder
+	matched := false
+	start := -1
+	flush := func(end int) {
+		word := text[start:end]
+		if slices.Contains(terms, strings.ToLower(word)) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+			matched = true
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = -1
+	}
+	for i, r := range text {
+		switch {
+		case isTermRune(r) && start < 0:
+			start = i
+		case !isTermRune(r):
+			if start >= 0 {
+				flush(i)
+			}
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+	return b.String(), matched
+}
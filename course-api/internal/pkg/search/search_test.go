@@ -0,0 +1,96 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func ids(hits []Hit) []string {
+	out := make([]string, len(hits))
+	for i, hit := range hits {
+		out[i] = hit.ID
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(Document{ID: "1", Name: "Go for beginners", Technology: []string{"Go"}})
+	ix.Put(Document{ID: "2", Name: "Go microservices with gRPC", Technology: []string{"Go", "gRPC"}})
+	ix.Put(Document{ID: "3", Name: "Advanced gRPC", Technology: []string{"gRPC"}})
+	ix.Put(Document{ID: "4", Name: "Rust", Technology: []string{"Rust"}})
+	ix.Put(Document{ID: "5", Name: "Kubernetes", Technology: []string{"Go"}})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// a name match outweighs a technology match
+		{"go", []string{"1", "2", "5"}},
+		{"GRPC", []string{"3", "2"}},
+		// matching both terms ranks first, then the rarer term
+		{"go grpc", []string{"2", "3", "1", "5"}},
+		{"rust", []string{"4"}},
+		{"python", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ids(ix.Search(Terms(tt.query))); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPutReplacesAndRemove(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(Document{ID: "1", Name: "Go", Technology: []string{"Go"}})
+	ix.Put(Document{ID: "1", Name: "Rust", Technology: []string{"Rust"}})
+	if ix.Len() != 1 {
+		t.Errorf("%d documents after replacing one, want 1", ix.Len())
+	}
+	if hits := ix.Search([]string{"go"}); len(hits) != 0 {
+		t.Errorf("old terms still match: %v", hits)
+	}
+	if hits := ix.Search([]string{"rust"}); len(hits) != 1 {
+		t.Errorf("new terms do not match: %v", hits)
+	}
+	ix.Remove("1")
+	ix.Remove("unknown")
+	if ix.Len() != 0 || len(ix.postings) != 0 {
+		t.Errorf("index not empty after removing everything: %d docs, %d terms", ix.Len(), len(ix.postings))
+	}
+}
+
+// equal scores are ordered by name, then id
+func TestSearchTies(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(Document{ID: "b", Name: "go Course"})
+	ix.Put(Document{ID: "a", Name: "Go course"})
+	ix.Put(Document{ID: "c", Name: "Go"})
+	if got := ids(ix.Search([]string{"go"})); !slices.Equal(got, []string{"c", "a", "b"}) {
+		t.Errorf("ties ordered %v, want c, a, b", got)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	if got := Terms("Go, go! gRPC-Go  Ünïcode 2024"); !slices.Equal(got, []string{"go", "grpc", "ünïcode", "2024"}) {
+		t.Errorf("Terms = %q", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text, want string
+		matched    bool
+	}{
+		{"Go for <beginners>", "<mark>Go</mark> for &lt;beginners&gt;", true},
+		{"gRPC & Go", "<mark>gRPC</mark> &amp; <mark>Go</mark>", true},
+		// whole words only
+		{"Gopher", "Gopher", false},
+	}
+	for _, tt := range tests {
+		got, matched := Highlight(tt.text, []string{"go", "grpc"})
+		if got != tt.want || matched != tt.matched {
+			t.Errorf("Highlight(%q) = %q, %v, want %q, %v", tt.text, got, matched, tt.want, tt.matched)
+		}
+	}
+}
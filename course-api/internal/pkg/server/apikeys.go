@@ -0,0 +1,154 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/logging"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// last_used_at is only written when it is older than this, so a busy key
+// does not turn every read into a write
+const apiKeyTouchInterval = time.Minute
+
+// apiKeyMiddleware authenticates requests carrying an X-API-Key header. The
+// key's scopes end up on the request context as claims, requireRole then
+// treats them like a verified token. Requests without the header, and all
+// requests while auth is off, pass through.
+//
+// Failed lookups take a token from the client ip's bucket, and once it is
+// empty keys are not looked up at all, so guessing keys costs the caller its
+// bucket rather than the database a query per guess. Valid keys only count
+// against their own bucket in rateLimitMiddleware.
+func (s *ApiServer) apiKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plaintext := r.Header.Get(apiKeyHeader)
+		if plaintext == "" || !s.Config.Auth.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client := "ip:" + s.clientIP(r)
+		if s.exhausted(w, r, client) {
+			return
+		}
+		rejected := func() {
+			if s.allow(w, r, client) {
+				writeProblem(w, r, http.StatusUnauthorized, "the api key is invalid, expired or revoked")
+			}
+		}
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		key, err := s.Db.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(plaintext))
+		if err == nil && !key.Active(now) {
+			err = database.ErrNotFound
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			rejected()
+			return
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		id, err := uuid.Parse(key.Id)
+		if err != nil {
+			rejected()
+			return
+		}
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+			if err := s.Db.TouchAPIKey(r.Context(), id, now); err != nil {
+				logging.FromContext(r.Context()).Warn("could not record api key use", "api_key", key.Id, "err", err)
+			}
+		}
+		ctx := auth.NewContext(r.Context(), auth.APIKeyClaims(key.Id, key.Scopes))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// admin routes
+
+func (s *ApiServer) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.Db.ListAPIKeys(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, keys)
+}
+
+func (s *ApiServer) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var params models.CreateAPIKeyParams
+	if !decodeBody(w, r, &params) {
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if err := params.Validate(now); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	plaintext, hash, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	key := models.APIKey{
+		Id:        uuid.New().String(),
+		Name:      params.Name,
+		Prefix:    prefix,
+		Scopes:    params.Scopes,
+		CreatedAt: now,
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := s.Db.CreateAPIKey(r.Context(), key, hash); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusCreated, models.NewAPIKey{APIKey: key, Key: plaintext})
+}
+
+// rotateAPIKey swaps the secret of a key, the old plaintext stops working
+// right away while name, scopes and expiry stay the same
+func (s *ApiServer) rotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	plaintext, hash, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	key, err := s.Db.RotateAPIKey(r.Context(), id, hash, prefix)
+	if errors.Is(err, database.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "api key not found or revoked")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, models.NewAPIKey{APIKey: key, Key: plaintext})
+}
+
+func (s *ApiServer) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	_, err := s.Db.RevokeAPIKey(r.Context(), id, time.Now().UTC().Truncate(time.Microsecond))
+	if errors.Is(err, database.ErrNotFound) {
+		writeProblem(w, r, http.StatusNotFound, "api key not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
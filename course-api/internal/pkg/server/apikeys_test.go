@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+// addAPIKey stores a key with scopes and returns its id and plaintext
+func addAPIKey(t *testing.T, s *ApiServer, expiresAt *time.Time, scopes ...string) (uuid.UUID, string) {
+	t.Helper()
+	plaintext, hash, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	key := models.APIKey{
+		Id:        id.String(),
+		Name:      "test",
+		Prefix:    prefix,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Add(-time.Hour),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.Db.CreateAPIKey(context.Background(), key, hash); err != nil {
+		t.Fatal(err)
+	}
+	return id, plaintext
+}
+
+func withAPIKey(r *http.Request, plaintext string) *http.Request {
+	r.Header.Set(apiKeyHeader, plaintext)
+	return r
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	s := authServer(t, true)
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	_, active := addAPIKey(t, s, &future, auth.RoleEditor)
+	_, expired := addAPIKey(t, s, &past, auth.RoleEditor)
+	revokedID, revoked := addAPIKey(t, s, nil, auth.RoleEditor)
+	if _, err := s.Db.RevokeAPIKey(context.Background(), revokedID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	unknown, _, _, _ := auth.GenerateAPIKey()
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"active", active, http.StatusOK},
+		{"expired", expired, http.StatusUnauthorized},
+		{"revoked", revoked, http.StatusUnauthorized},
+		{"unknown", unknown, http.StatusUnauthorized},
+		{"not a key", "hunter2", http.StatusUnauthorized},
+	}
+	handler := s.apiKeyMiddleware(s.requireRole(auth.RoleEditor, func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withAPIKey(httptest.NewRequest(http.MethodPost, "/course", nil), tt.key))
+		if w.Code != tt.status {
+			t.Errorf("%s key: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+
+	// without the header the request goes on to the bearer token check
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/course", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("no key: status %d, want 401 with a bearer challenge", w.Code)
+	}
+}
+
+func TestAPIKeyScopesAreRoles(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		role   string
+		status int
+	}{
+		{[]string{auth.RoleReader}, auth.RoleReader, http.StatusOK},
+		{[]string{auth.RoleReader}, auth.RoleEditor, http.StatusForbidden},
+		{[]string{auth.RoleEditor}, auth.RoleReader, http.StatusOK},
+		{[]string{auth.RoleEditor}, auth.RoleEditor, http.StatusOK},
+		{[]string{auth.RoleEditor}, auth.RoleAdmin, http.StatusForbidden},
+		{[]string{auth.RoleReader, auth.RoleAdmin}, auth.RoleAdmin, http.StatusOK},
+	}
+	s := authServer(t, true)
+	for _, tt := range tests {
+		_, plaintext := addAPIKey(t, s, nil, tt.scopes...)
+		var seen *auth.Claims
+		handler := s.apiKeyMiddleware(s.requireRole(tt.role, func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = auth.FromContext(r.Context())
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withAPIKey(httptest.NewRequest(http.MethodGet, "/", nil), plaintext))
+		if w.Code != tt.status {
+			t.Errorf("%v key on a %s route: status %d, want %d", tt.scopes, tt.role, w.Code, tt.status)
+		}
+		if tt.status == http.StatusOK && (seen == nil || len(seen.Roles) != len(tt.scopes)) {
+			t.Errorf("%v key: claims %+v", tt.scopes, seen)
+		}
+	}
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	s := authServer(t, true)
+	s.SetUpRoutes()
+	handler := s.apiKeyMiddleware(s.Handler)
+	admin := bearer(t, testSecret, time.Hour, auth.RoleAdmin)
+	rotate := func(id uuid.UUID) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/admin/api-keys/"+id.String()+"/rotate", nil)
+		r.Header.Set("Authorization", admin)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	use := func(plaintext string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, withAPIKey(httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil), plaintext))
+		return w.Code
+	}
+
+	id, old := addAPIKey(t, s, nil, auth.RoleAdmin)
+	w := rotate(id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("rotate: status %d: %s", w.Code, w.Body)
+	}
+	var rotated models.NewAPIKey
+	if err := json.Unmarshal(w.Body.Bytes(), &rotated); err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Id != id.String() || rotated.Key == old || rotated.Scopes[0] != auth.RoleAdmin {
+		t.Errorf("rotated key %+v, want the same key with a new secret", rotated)
+	}
+	if status := use(old); status != http.StatusUnauthorized {
+		t.Errorf("old secret after rotation: status %d, want 401", status)
+	}
+	if status := use(rotated.Key); status != http.StatusOK {
+		t.Errorf("new secret: status %d, want 200", status)
+	}
+
+	if _, err := s.Db.RevokeAPIKey(context.Background(), id, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if w := rotate(id); w.Code != http.StatusNotFound {
+		t.Errorf("rotating a revoked key: status %d, want 404", w.Code)
+	}
+	if status := use(rotated.Key); status != http.StatusUnauthorized {
+		t.Errorf("revoked key: status %d, want 401", status)
+	}
+	if w := rotate(uuid.New()); w.Code != http.StatusNotFound {
+		t.Errorf("rotating an unknown key: status %d, want 404", w.Code)
+	}
+}
+
+// countingStore counts api key lookups
+type countingStore struct {
+	database.Interface
+	lookups int
+}
+
+func (s *countingStore) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	s.lookups++
+	return s.Interface.GetAPIKeyByHash(ctx, hash)
+}
+
+// with auth off an api key is ignored, not looked up or rejected
+func TestAPIKeyIgnoredWithAuthOff(t *testing.T) {
+	s := authServer(t, false)
+	db := &countingStore{Interface: s.Db}
+	s.Db = db
+	handler := s.apiKeyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withAPIKey(httptest.NewRequest(http.MethodGet, "/courses", nil), "capi_unknown"))
+	if w.Code != http.StatusOK || db.lookups != 0 {
+		t.Errorf("status %d after %d lookups, want 200 without a lookup", w.Code, db.lookups)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/logging"
+)
+
+// requireRole lets the request through when the caller carries role (or a
+// higher one), either from an api key resolved by apiKeyMiddleware or from a
+// valid bearer token. The claims are put on the request context. With auth
+// disabled every request is let through.
+func (s *ApiServer) requireRole(role string, next http.HandlerFunc) http.Handler {
+	if !s.Config.Auth.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.FromContext(r.Context())
+		if !ok {
+			var err error
+			claims, err = s.verifyBearer(r)
+			if errors.Is(err, auth.ErrMissingToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="course-api"`)
+				writeProblem(w, r, http.StatusUnauthorized, "a bearer token or "+apiKeyHeader+" header is required")
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Info("rejected bearer token", "err", err)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="course-api", error="invalid_token"`)
+				writeProblem(w, r, http.StatusUnauthorized, "the bearer token is invalid or expired")
+				return
+			}
+		}
+		if !claims.HasRole(role) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="course-api", error="insufficient_scope"`)
+			writeProblem(w, r, http.StatusForbidden, "this action needs the "+role+" role")
+			return
+		}
+		next(w, r.WithContext(auth.NewContext(r.Context(), claims)))
+	})
+}
+
+// verifyBearer checks the Authorization header. Without a verifier only api
+// keys are accepted, so any token is rejected.
+func (s *ApiServer) verifyBearer(r *http.Request) (*auth.Claims, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, auth.ErrMissingToken
+	}
+	if s.Auth == nil {
+		return nil, fmt.Errorf("%w: no jwt keys configured", auth.ErrInvalidToken)
+	}
+	return s.Auth.Verify(token)
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
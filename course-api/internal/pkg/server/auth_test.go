@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+const testSecret = "test-secret"
+
+// bearer returns an Authorization header value for a token with roles
+func bearer(t *testing.T, secret string, expiresIn time.Duration, roles ...string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn))},
+		Roles:            roles,
+	})
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+func authServer(t *testing.T, enabled bool) *ApiServer {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Auth.Enabled = enabled
+	cfg.Auth.HMACSecret = testSecret
+	s := NewApiServer(cfg, mux.NewRouter(), database.NewMemoryStore())
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Auth = verifier
+	return s
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		challenge     string
+	}{
+		{"no token", "", http.StatusUnauthorized, `Bearer realm="course-api"`},
+		{"not a bearer token", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, `Bearer realm="course-api"`},
+		{"garbage token", "Bearer not.a.jwt", http.StatusUnauthorized, `error="invalid_token"`},
+		{"wrong secret", bearer(t, "other-secret", time.Hour, auth.RoleEditor), http.StatusUnauthorized, `error="invalid_token"`},
+		{"expired", bearer(t, testSecret, -time.Hour, auth.RoleEditor), http.StatusUnauthorized, `error="invalid_token"`},
+		{"reader on an editor route", bearer(t, testSecret, time.Hour, auth.RoleReader), http.StatusForbidden, `error="insufficient_scope"`},
+		{"no roles", bearer(t, testSecret, time.Hour), http.StatusForbidden, `error="insufficient_scope"`},
+		{"editor", bearer(t, testSecret, time.Hour, auth.RoleEditor), http.StatusOK, ""},
+		{"admin", bearer(t, testSecret, time.Hour, auth.RoleAdmin), http.StatusOK, ""},
+	}
+	s := authServer(t, true)
+	var seen *auth.Claims
+	handler := s.requireRole(auth.RoleEditor, func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = auth.FromContext(r.Context())
+	})
+	for _, tt := range tests {
+		seen = nil
+		r := httptest.NewRequest(http.MethodPost, "/course", nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if challenge := w.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, tt.challenge) || (tt.challenge == "") != (challenge == "") {
+			t.Errorf("%s: WWW-Authenticate %q, want %q", tt.name, challenge, tt.challenge)
+		}
+		if tt.status != http.StatusOK {
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%s: content type %q, want a problem", tt.name, ct)
+			}
+		} else if seen == nil || seen.Subject != "user-1" {
+			t.Errorf("%s: claims %+v not passed on the context", tt.name, seen)
+		}
+	}
+}
+
+// api keys resolved by apiKeyMiddleware arrive as claims on the context
+func TestRequireRoleUsesContextClaims(t *testing.T) {
+	s := authServer(t, true)
+	handler := s.requireRole(auth.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {})
+	for scope, status := range map[string]int{auth.RoleEditor: http.StatusForbidden, auth.RoleAdmin: http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+		r = r.WithContext(auth.NewContext(r.Context(), auth.APIKeyClaims("key-1", []string{scope})))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != status {
+			t.Errorf("%s key: status %d, want %d", scope, w.Code, status)
+		}
+	}
+}
+
+func TestRequireRoleWithAuthDisabled(t *testing.T) {
+	s := authServer(t, false)
+	handler := s.requireRole(auth.RoleEditor, func(w http.ResponseWriter, r *http.Request) {})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/course", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status %d, want 200 with auth disabled", w.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/logging"
+	"github.com/course-api/internal/pkg/models"
+)
+
+// createCourses takes an array of courses. mode=atomic (the default) creates
+// all of them or none, any invalid course fails the request with a 422 and a
+// course the database rejects with its error, both naming the course.
+// mode=best_effort creates what it can and answers 207 with a result per
+// course.
+func (s *ApiServer) createCourses(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = models.BulkAtomic
+	}
+	if mode != models.BulkAtomic && mode != models.BulkBestEffort {
+		writeQueryError(w, r, models.NewFieldError("mode", "must be atomic or best_effort"))
+		return
+	}
+	var params []models.CreateCourseParams
+	if !decodeBody(w, r, &params) {
+		return
+	}
+	if len(params) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "provide at least one course")
+		return
+	}
+	if max := s.Config.Server.BulkMaxBatchSize; len(params) > max {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d courses can be created at once", max))
+		return
+	}
+
+	response := models.BulkResponse{Mode: mode, Results: make([]models.BulkItemResult, len(params))}
+	var valid []models.CreateCourseParams
+	// index in the request of each valid course
+	var validIndex []int
+	var invalid []models.FieldError
+	for i := range params {
+		response.Results[i].Index = i
+		var validationErr models.ValidationError
+		if err := params[i].Validate(); errors.As(err, &validationErr) {
+			response.Results[i].Status = http.StatusUnprocessableEntity
+			response.Results[i].Error = "course contains invalid fields"
+			response.Results[i].Errors = validationErr
+			for _, e := range validationErr {
+				invalid = append(invalid, models.FieldError{Field: fmt.Sprintf("[%d].%s", i, e.Field), Message: e.Message})
+			}
+			continue
+		}
+		valid = append(valid, params[i])
+		validIndex = append(validIndex, i)
+	}
+	atomic := mode == models.BulkAtomic
+	if atomic && len(invalid) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "request contains invalid courses, none were created", invalid...)
+		return
+	}
+
+	if len(valid) > 0 {
+		results, err := s.Db.CreateMany(r.Context(), valid, atomic)
+		var itemErr *database.BulkItemError
+		if errors.As(err, &itemErr) {
+			index := validIndex[itemErr.Index]
+			status, message := bulkItemError(r, itemErr.Err)
+			writeProblem(w, r, status, fmt.Sprintf("course %d could not be created, none were created", index),
+				models.FieldError{Field: fmt.Sprintf("[%d]", index), Message: message})
+			return
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		for j, result := range results {
+			item := &response.Results[validIndex[j]]
+			if result.Err != nil {
+				item.Status, item.Error = bulkItemError(r, result.Err)
+				continue
+			}
+			item.Status = http.StatusCreated
+			item.Id = result.Course.Id
+		}
+	}
+	for _, item := range response.Results {
+		if item.Status == http.StatusCreated {
+			response.Created++
+		} else {
+			response.Failed++
+		}
+	}
+	status := http.StatusCreated
+	if !atomic {
+		status = http.StatusMultiStatus
+	}
+	writeResponse(w, r, status, response)
+}
+
+// bulkItemError is the status and message writeError would have answered
+// with for one course
+func bulkItemError(r *http.Request, err error) (int, string) {
+	switch {
+	case errors.Is(err, database.ErrConflict):
+		return http.StatusConflict, "course conflicts with an existing record"
+	case errors.Is(err, database.ErrInvalidData):
+		logging.FromContext(r.Context()).Warn("rejected by database", "err", err)
+		return http.StatusUnprocessableEntity, "course data was rejected by the database"
+	case errors.Is(err, database.ErrUnavailable):
+		logging.FromContext(r.Context()).Error("bulk item failed", "err", err)
+		return http.StatusServiceUnavailable, "database is unavailable, try again later"
+	default:
+		logging.FromContext(r.Context()).Error("bulk item failed", "err", err)
+		return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+)
+
+// conflictingStore fails atomic creates on the course at index
+type conflictingStore struct {
+	database.Interface
+	index int
+}
+
+func (c conflictingStore) CreateMany(ctx context.Context, params []models.CreateCourseParams, atomic bool) ([]database.BulkResult, error) {
+	err := &database.Error{Op: "create course", Kind: database.ErrConflict, Err: fmt.Errorf("duplicate")}
+	return nil, &database.BulkItemError{Index: c.index, Err: err}
+}
+
+func postBulk(t *testing.T, s *ApiServer, mode, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/courses/bulk?mode="+mode, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.createCourses(w, r)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	return problem
+}
+
+const bulkBody = `[{"name": "Go", "price": 10, "technology": ["Go"]}, {"name": "", "price": -1, "technology": ["Go"]}, {"name": "Rust", "price": 10, "technology": ["Rust"]}]`
+
+func TestCreateCoursesAtomicRejectsInvalid(t *testing.T) {
+	s := authServer(t, false)
+	w := postBulk(t, s, models.BulkAtomic, bulkBody)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", w.Code)
+	}
+	problem := decodeProblem(t, w)
+	for _, e := range problem.Errors {
+		if !strings.HasPrefix(e.Field, "[1].") {
+			t.Errorf("error %+v, want it keyed by [1]", e)
+		}
+	}
+	if n := countCourses(t, s); n != 0 {
+		t.Errorf("%d courses created", n)
+	}
+}
+
+func TestCreateCoursesBestEffort(t *testing.T) {
+	s := authServer(t, false)
+	w := postBulk(t, s, models.BulkBestEffort, bulkBody)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("status %d, want 207", w.Code)
+	}
+	var response models.BulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Created != 2 || response.Failed != 1 {
+		t.Errorf("created %d, failed %d, want 2 and 1", response.Created, response.Failed)
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusUnprocessableEntity, http.StatusCreated} {
+		if item := response.Results[i]; item.Index != i || item.Status != want {
+			t.Errorf("result %d: %+v, want status %d", i, item, want)
+		}
+	}
+}
+
+// a course the database rejects in an atomic create is named like an invalid
+// one
+func TestCreateCoursesAtomicNamesRejectedCourse(t *testing.T) {
+	s := authServer(t, false)
+	s.Db = conflictingStore{Interface: s.Db, index: 1}
+	w := postBulk(t, s, models.BulkAtomic, `[{"name": "Go", "price": 10, "technology": ["Go"]}, {"name": "Rust", "price": 10, "technology": ["Rust"]}]`)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status %d, want 409", w.Code)
+	}
+	problem := decodeProblem(t, w)
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "[1]" {
+		t.Errorf("errors %+v, want one keyed by [1]", problem.Errors)
+	}
+}
+
+func TestCreateCoursesLimits(t *testing.T) {
+	s := authServer(t, false)
+	s.Config.Server.BulkMaxBatchSize = 2
+	tests := []struct {
+		mode, body string
+		status     int
+	}{
+		{models.BulkAtomic, `[]`, http.StatusBadRequest},
+		{"all_or_nothing", `[{"name": "Go", "price": 10, "technology": ["Go"]}]`, http.StatusBadRequest},
+		{models.BulkAtomic, bulkBody, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if w := postBulk(t, s, tt.mode, tt.body); w.Code != tt.status {
+			t.Errorf("mode %s, body %s: status %d, want %d", tt.mode, tt.body, w.Code, tt.status)
+		}
+	}
+}
+
+func countCourses(t *testing.T, s *ApiServer) int {
+	t.Helper()
+	page, err := s.Db.GetAll(context.Background(), models.ListCoursesParams{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Total
+}
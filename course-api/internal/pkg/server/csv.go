@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/course-api/internal/pkg/logging"
+	"github.com/course-api/internal/pkg/models"
+)
+
+// exportCourses streams every course as csv, sorted by name. Courses are read
+// a page at a time so the catalog is never held in memory, and each page gets
+// the server's write timeout afresh so a large catalog is not cut off.
+func (s *ApiServer) exportCourses(w http.ResponseWriter, r *http.Request) {
+	if format := r.URL.Query().Get("format"); format != "" && format != "csv" {
+		writeQueryError(w, r, models.NewFieldError("format", "must be csv"))
+		return
+	}
+	params := models.ListCoursesParams{Limit: models.MaxPageLimit, Sort: []models.SortField{{Field: "name"}}}
+	page, err := s.Db.GetAll(r.Context(), params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="courses.csv"`)
+	out := csv.NewWriter(w)
+	out.Write(models.CSVColumns)
+	rc := http.NewResponseController(w)
+	for {
+		if timeout := s.Config.Server.WriteTimeout; timeout > 0 {
+			if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logging.FromContext(r.Context()).Warn("could not extend the export write deadline", "err", err)
+			}
+		}
+		for _, course := range page.Courses {
+			out.Write(course.CSVRecord())
+		}
+		out.Flush()
+		// the client went away
+		if out.Error() != nil || page.NextCursor == "" {
+			return
+		}
+		cursor, err := models.DecodeCursor(page.NextCursor)
+		if err == nil {
+			params.Cursor = &cursor
+			page, err = s.Db.GetAll(r.Context(), params)
+		}
+		if err != nil {
+			// the 200 is out already, dropping the connection is the only way
+			// left to tell the client the file is incomplete
+			logging.FromContext(r.Context()).Error("export failed", "err", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// importCourses reads a csv file in the export format and creates or
+// replaces its courses by id, all in one transaction. Any invalid row fails
+// the whole import with the errors of every row. dry_run=true checks the
+// file and reports what would change without applying it.
+func (s *ApiServer) importCourses(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseBool(r.URL.Query(), "dry_run")
+	if err != nil {
+		writeQueryError(w, r, err)
+		return
+	}
+	file, ok := csvUpload(w, r)
+	if !ok {
+		return
+	}
+	reader, err := models.NewCSVReader(file)
+	var validationErr models.ValidationError
+	if errors.As(err, &validationErr) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "csv header is invalid, nothing was imported", validationErr...)
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "could not read csv: "+err.Error())
+		return
+	}
+
+	var courses []models.Course
+	var rowErrs models.ValidationError
+	// line each id was first seen on
+	idLines := map[string]int{}
+	for rows := 1; ; rows++ {
+		course, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if max := s.Config.Server.ImportMaxRows; rows > max {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d rows can be imported at once", max))
+			return
+		}
+		if errors.As(err, &validationErr) {
+			rowErrs = append(rowErrs, validationErr...)
+			continue
+		}
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "could not read csv: "+err.Error())
+			return
+		}
+		if course.Id != "" {
+			if first, ok := idLines[course.Id]; ok {
+				rowErrs = append(rowErrs, models.FieldError{Line: line, Field: "id", Message: fmt.Sprintf("is already used on line %d", first)})
+				continue
+			}
+			idLines[course.Id] = line
+		}
+		courses = append(courses, course)
+	}
+	if len(rowErrs) > 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "csv contains invalid rows, nothing was imported", rowErrs...)
+		return
+	}
+	if len(courses) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "csv has no rows")
+		return
+	}
+	summary, err := s.Db.Import(r.Context(), courses, dryRun)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, summary)
+}
+
+// csvUpload returns the uploaded csv file, sent either as the body with
+// Content-Type text/csv or as the file field of a multipart/form-data form
+func csvUpload(w http.ResponseWriter, r *http.Request) (io.Reader, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return r.Body, true
+	case "multipart/form-data":
+		form, err := r.MultipartReader()
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "could not parse multipart form: "+err.Error())
+			return nil, false
+		}
+		for {
+			part, err := form.NextPart()
+			if errors.Is(err, io.EOF) {
+				writeProblem(w, r, http.StatusBadRequest, "multipart form has no file field")
+				return nil, false
+			}
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "could not parse multipart form: "+err.Error())
+				return nil, false
+			}
+			if part.FormName() == "file" {
+				return part, true
+			}
+		}
+	default:
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "send the csv as text/csv or as the file field of a multipart/form-data form")
+		return nil, false
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+)
+
+// slowStore takes delay to read each page
+type slowStore struct {
+	database.Interface
+	delay time.Duration
+}
+
+// GetAll gives up early when ctx is done, so a handler cut off by a test does
+// not outlive it
+func (s slowStore) GetAll(ctx context.Context, params models.ListCoursesParams) (models.CoursePage, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return models.CoursePage{}, ctx.Err()
+	}
+	return s.Interface.GetAll(ctx, params)
+}
+
+func seedCourses(t *testing.T, s *ApiServer, n int) {
+	t.Helper()
+	params := make([]models.CreateCourseParams, n)
+	for i := range params {
+		params[i] = models.CreateCourseParams{Name: fmt.Sprintf("course %03d", i), Price: 10, Technology: []string{"Go"}}
+	}
+	if _, err := s.Db.CreateMany(context.Background(), params, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportEscapesFormulas(t *testing.T) {
+	s := authServer(t, false)
+	if _, err := s.Db.Create(context.Background(), models.CreateCourseParams{Name: "=cmd|'/c calc'!A0", Price: 10, Technology: []string{"@Go"}}); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.exportCourses(w, httptest.NewRequest(http.MethodGet, "/courses/export", nil))
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][1] != "'=cmd|'/c calc'!A0" || records[1][3] != "'@Go" {
+		t.Errorf("exported %q", records)
+	}
+}
+
+// each page gets a fresh write deadline, the whole export may take longer
+// than WRITE_TIMEOUT
+func TestExportOutlivesWriteTimeout(t *testing.T) {
+	s := authServer(t, false)
+	seedCourses(t, s, 3*models.MaxPageLimit)
+	timeout := 300 * time.Millisecond
+	s.Config.Server.WriteTimeout = timeout
+	s.Db = slowStore{Interface: s.Db, delay: timeout / 2}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(s.exportCourses))
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("export cut off after %d bytes: %v", len(body), err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3*models.MaxPageLimit+1 {
+		t.Errorf("%d rows, want a header and %d courses", len(records), 3*models.MaxPageLimit)
+	}
+}
+
+func postImport(t *testing.T, s *ApiServer, query, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/courses/import"+query, strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	s.importCourses(w, r)
+	return w
+}
+
+// an export with escaped formulas imports back unchanged
+func TestImportExportRoundTrip(t *testing.T) {
+	s := authServer(t, false)
+	if _, err := s.Db.Create(context.Background(), models.CreateCourseParams{Name: "-10% off", Price: 10, Technology: []string{"=Go", "Rust"}}); err != nil {
+		t.Fatal(err)
+	}
+	export := func() string {
+		w := httptest.NewRecorder()
+		s.exportCourses(w, httptest.NewRequest(http.MethodGet, "/courses/export", nil))
+		return w.Body.String()
+	}
+	before := export()
+	if w := postImport(t, s, "", before); w.Code != http.StatusOK {
+		t.Fatalf("import: status %d: %s", w.Code, w.Body)
+	}
+	if after := export(); after != before {
+		t.Errorf("exported %q after the import, want %q", after, before)
+	}
+}
+
+func TestImportReportsEveryBadRow(t *testing.T) {
+	s := authServer(t, false)
+	body := "name,price,technology\nGo,10,Go\n,10,Go\nRust,free,Rust\n"
+	w := postImport(t, s, "", body)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", w.Code)
+	}
+	lines := map[int]bool{}
+	for _, e := range decodeProblem(t, w).Errors {
+		lines[e.Line] = true
+	}
+	if !lines[3] || !lines[4] || lines[2] {
+		t.Errorf("errors on lines %v, want 3 and 4", lines)
+	}
+	if n := countCourses(t, s); n != 0 {
+		t.Errorf("%d courses imported from a file with bad rows", n)
+	}
+
+	w = postImport(t, s, "?dry_run=true", "name,price,technology\nGo,10,Go\n")
+	if w.Code != http.StatusOK || countCourses(t, s) != 0 {
+		t.Errorf("dry run: status %d, %d courses", w.Code, countCourses(t, s))
+	}
+}
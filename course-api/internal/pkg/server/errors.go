@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/course-api/internal/pkg/codec"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/logging"
+	"github.com/course-api/internal/pkg/models"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error document
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Errors   []models.FieldError `json:"errors,omitempty"`
+}
+
+// problem types clients can branch on, relative to the api root
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            "/problems/bad-request",
+	http.StatusUnauthorized:          "/problems/unauthorized",
+	http.StatusForbidden:             "/problems/forbidden",
+	http.StatusNotFound:              "/problems/not-found",
+	http.StatusMethodNotAllowed:      "/problems/method-not-allowed",
+	http.StatusNotAcceptable:         "/problems/not-acceptable",
+	http.StatusConflict:              "/problems/conflict",
+	http.StatusPreconditionFailed:    "/problems/precondition-failed",
+	http.StatusRequestEntityTooLarge: "/problems/too-large",
+	http.StatusUnsupportedMediaType:  "/problems/unsupported-media-type",
+	http.StatusUnprocessableEntity:   "/problems/validation-error",
+	http.StatusPreconditionRequired:  "/problems/precondition-required",
+	http.StatusTooManyRequests:       "/problems/rate-limited",
+	http.StatusServiceUnavailable:    "/problems/unavailable",
+	http.StatusGatewayTimeout:        "/problems/timeout",
+}
+
+// writeProblem sends the problem in the format the client accepts, json when
+// it accepts none
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, fieldErrors ...models.FieldError) {
+	problemType, ok := problemTypes[status]
+	if !ok {
+		problemType = "about:blank"
+	}
+	c := codec.Negotiate(r.Header.Get("Accept"))
+	if c == nil {
+		c = codec.JSON
+	}
+	body, err := c.Marshal(Problem{
+		Type:     problemType,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   fieldErrors,
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("encode problem failed", "err", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Set("Content-Type", c.ProblemType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeError maps errors coming from the models and database packages onto
+// a status code. Anything unknown is logged and hidden behind a 500.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeProblem(w, r, http.StatusUnprocessableEntity, "request contains invalid fields", validationErr...)
+	case errors.Is(err, models.ErrInvalidPatch):
+		writeProblem(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, database.ErrNotFound):
+		writeProblem(w, r, http.StatusNotFound, "course not found")
+	case errors.Is(err, database.ErrVersionMismatch):
+		writeProblem(w, r, http.StatusPreconditionFailed, "course was changed since that version, GET it again for the current ETag")
+	case errors.Is(err, database.ErrNotDeleted):
+		writeProblem(w, r, http.StatusConflict, "course is not deleted")
+	case errors.Is(err, database.ErrConflict):
+		writeProblem(w, r, http.StatusConflict, "course conflicts with an existing record")
+	case errors.Is(err, database.ErrInvalidData):
+		logging.FromContext(r.Context()).Warn("rejected by database", "err", err)
+		writeProblem(w, r, http.StatusUnprocessableEntity, "course data was rejected by the database")
+	case errors.Is(err, database.ErrUnavailable):
+		logging.FromContext(r.Context()).Error("request failed", "err", err)
+		w.Header().Set("Retry-After", "5")
+		writeProblem(w, r, http.StatusServiceUnavailable, "database is unavailable, try again later")
+	case errors.Is(err, context.DeadlineExceeded):
+		writeProblem(w, r, http.StatusGatewayTimeout, "request timed out")
+	default:
+		logging.FromContext(r.Context()).Error("request failed", "err", err)
+		writeProblem(w, r, http.StatusInternalServerError, "oops something went wrong")
+	}
+}
+
+// writeResponse sends v in the format the Accept header asks for, json when
+// there is none. negotiationMiddleware has turned away requests accepting
+// none of them.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, v any) {
+	c := responseCodec(r)
+	body, err := c.Marshal(v)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("encode %s response: %w", c.MediaType, err))
+		return
+	}
+	w.Header().Set("Content-Type", c.MediaType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// responseCodec is the format writeResponse answers r in
+func responseCodec(r *http.Request) *codec.Codec {
+	if c := codec.Negotiate(r.Header.Get("Accept")); c != nil {
+		return c
+	}
+	return codec.JSON
+}
+
+// fixedFormat are the routes that answer in one format whatever Accept says
+func fixedFormat(path string) bool {
+	switch path {
+	case "/", "/courses/export", "/metrics", "/openapi.json", "/docs":
+		return true
+	}
+	return false
+}
+
+// negotiationMiddleware answers 406 when the Accept header rules out every
+// response format, before the handler has changed anything
+func (s *ApiServer) negotiationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fixedFormat(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		if codec.Negotiate(r.Header.Get("Accept")) == nil {
+			writeProblem(w, r, http.StatusNotAcceptable, "cannot answer in any of the accepted formats, supported are "+codec.MediaTypes())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, "no route matches "+r.URL.Path)
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
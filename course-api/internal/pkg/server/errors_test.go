@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestWriteError(t *testing.T) {
+	dbErr := func(kind error) error {
+		return &database.Error{Op: "get course", Kind: kind, Err: errors.New("driver said no")}
+	}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+	}{
+		{"validation", models.NewFieldError("price", "must be greater than 0"), http.StatusUnprocessableEntity, "/problems/validation-error"},
+		{"bad patch", fmt.Errorf("%w: op is missing", models.ErrInvalidPatch), http.StatusUnprocessableEntity, "/problems/validation-error"},
+		{"not found", dbErr(database.ErrNotFound), http.StatusNotFound, "/problems/not-found"},
+		{"version", dbErr(database.ErrVersionMismatch), http.StatusPreconditionFailed, "/problems/precondition-failed"},
+		{"not deleted", dbErr(database.ErrNotDeleted), http.StatusConflict, "/problems/conflict"},
+		{"conflict", &database.DuplicateKeyError{Id: "x"}, http.StatusConflict, "/problems/conflict"},
+		{"invalid data", dbErr(database.ErrInvalidData), http.StatusUnprocessableEntity, "/problems/validation-error"},
+		{"unavailable", dbErr(database.ErrUnavailable), http.StatusServiceUnavailable, "/problems/unavailable"},
+		{"timeout", fmt.Errorf("list: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "/problems/timeout"},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, "about:blank"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeError(w, httptest.NewRequest(http.MethodGet, "/courses/1", nil), tt.err)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+			t.Errorf("%s: content type %q", tt.name, ct)
+		}
+		problem := decodeProblem(t, w)
+		if problem.Type != tt.typ || problem.Status != tt.status || problem.Title != http.StatusText(tt.status) || problem.Instance != "/courses/1" {
+			t.Errorf("%s: problem %+v", tt.name, problem)
+		}
+		// driver details stay in the log
+		if strings.Contains(w.Body.String(), "driver said no") || strings.Contains(w.Body.String(), "boom") {
+			t.Errorf("%s: body leaks the error: %s", tt.name, w.Body)
+		}
+	}
+}
+
+func TestValidationProblemListsFields(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := models.ValidationError{{Field: "name", Message: "is required"}, {Field: "price", Message: "must be greater than 0"}}
+	writeError(w, httptest.NewRequest(http.MethodPost, "/course", nil), err)
+	problem := decodeProblem(t, w)
+	if len(problem.Errors) != 2 || problem.Errors[0].Field != "name" || problem.Errors[1].Field != "price" {
+		t.Errorf("errors %+v", problem.Errors)
+	}
+}
+
+func TestProblemFollowsAccept(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/courses/1", nil)
+	r.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	writeProblem(w, r, http.StatusNotFound, "course not found")
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+xml" {
+		t.Errorf("content type %q, want application/problem+xml", ct)
+	}
+	var problem struct {
+		Status int    `xml:"status"`
+		Detail string `xml:"detail"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Status != http.StatusNotFound || problem.Detail != "course not found" {
+		t.Errorf("got %+v, %v from %s", problem, err, w.Body)
+	}
+}
+
+// unknown routes and methods answer with problems too
+func TestRouterProblems(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	for _, tt := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/nope", http.StatusNotFound},
+		{http.MethodPatch, "/courses", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/courses/not-a-uuid", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		var problem Problem
+		if w.Code != tt.status || json.Unmarshal(w.Body.Bytes(), &problem) != nil || problem.Status != tt.status {
+			t.Errorf("%s %s: status %d, body %s, want a %d problem", tt.method, tt.path, w.Code, w.Body, tt.status)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/course-api/internal/pkg/codec"
+	"github.com/google/uuid"
+)
+
+// etag is the strong entity tag of a course version in the format r is
+// answered in, e.g. "3-json". Each format is a different representation with
+// its own bytes, so the tags differ too and caches keep them apart (the
+// responses also carry Vary: Accept).
+func etag(r *http.Request, version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + "-" + responseCodec(r).Name() + `"`
+}
+
+// setETag sends the tag of version in the response format
+func setETag(w http.ResponseWriter, r *http.Request, version int64) {
+	w.Header().Set("ETag", etag(r, version))
+}
+
+// ifMatch reads the version a write of course id is conditional on from
+// If-Match. "*" gives 0, any version. Without the header required writes get
+// a 428, and a header naming no course version gets a 412 as it can never
+// match. Every format of a version is current at once, so the tag of any of
+// them will do.
+//
+// The condition holds when any listed tag matches (RFC 7232 3.1). A list
+// naming several versions is resolved to the current one if it is among them;
+// otherwise the first is returned and the store rejects the write with a 412.
+// The store checks the version again as it writes, so a change in between
+// still fails the precondition.
+func (s *ApiServer) ifMatch(w http.ResponseWriter, r *http.Request, id uuid.UUID, required bool) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if required {
+			writeProblem(w, r, http.StatusPreconditionRequired, "If-Match is required, send the ETag of the course from GET /courses/{id}")
+			return 0, false
+		}
+		return 0, true
+	}
+	if header == "*" {
+		return 0, true
+	}
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		// weak tags never match If-Match
+		if version, ok := parseETag(strings.TrimSpace(tag)); ok && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		writeProblem(w, r, http.StatusPreconditionFailed, "If-Match does not name a version of the course")
+		return 0, false
+	case 1:
+		return versions[0], true
+	}
+	// a missing course is left for the write to report
+	if course, err := s.Db.GetByID(r.Context(), id, true); err == nil && slices.Contains(versions, course.Version) {
+		return course.Version, true
+	}
+	return versions[0], true
+}
+
+// parseETag reads the version from a strong tag made by etag
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	number, format, ok := strings.Cut(tag[1:len(tag)-1], "-")
+	if !ok || !isFormat(format) {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(number, 10, 64)
+	return version, err == nil && version > 0
+}
+
+func isFormat(name string) bool {
+	for _, c := range codec.All {
+		if c.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether If-None-Match lists the current version in the
+// response format, weak or strong, or is "*"
+func notModified(r *http.Request, version int64) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(r, version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
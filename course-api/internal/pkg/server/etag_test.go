@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+// updatedCourse stores a course and updates it once, to version 2
+func updatedCourse(t *testing.T, s *ApiServer) models.Course {
+	t.Helper()
+	ctx := context.Background()
+	course, err := s.Db.Create(ctx, models.CreateCourseParams{Name: "Go", Price: 10, Technology: []string{"Go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	course, err = s.Db.Update(ctx, parseUUID(t, course.Id), models.UpdateCourseParams{Name: "Go", Price: 12, Technology: []string{"Go"}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return course
+}
+
+func parseUUID(t *testing.T, id string) uuid.UUID {
+	t.Helper()
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		version int64
+		ok      bool
+	}{
+		{`"3-json"`, 3, true},
+		{`"12-msgpack"`, 12, true},
+		{`"3-xml"`, 3, true},
+		{`"3"`, 0, false},
+		{`"3-csv"`, 0, false},
+		{`"0-json"`, 0, false},
+		{`"-1-json"`, 0, false},
+		{`W/"3-json"`, 0, false},
+		{`3-json`, 0, false},
+		{`""`, 0, false},
+	}
+	for _, tt := range tests {
+		version, ok := parseETag(tt.tag)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("parseETag(%s) = %d, %v, want %d, %v", tt.tag, version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	s := authServer(t, false)
+	course := updatedCourse(t, s)
+	tests := []struct {
+		header   string
+		required bool
+		version  int64
+		status   int
+	}{
+		{"", true, 0, http.StatusPreconditionRequired},
+		{"", false, 0, 0},
+		{"*", true, 0, 0},
+		{`"4-json"`, true, 4, 0},
+		// a tag of another format names the same version
+		{`"4-yaml"`, true, 4, 0},
+		{`W/"4-json", "5-xml"`, true, 5, 0},
+		{`W/"4-json"`, true, 0, http.StatusPreconditionFailed},
+		{`"abc"`, false, 0, http.StatusPreconditionFailed},
+		// any listed tag may match, the current version is picked out
+		{`"1-json", "2-json"`, true, 2, 0},
+		{`"2-xml", "1-json"`, true, 2, 0},
+		// none current, the store turns the first one down
+		{`"7-json", "8-json"`, true, 7, 0},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/courses/x", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+		version, ok := s.ifMatch(w, r, parseUUID(t, course.Id), tt.required)
+		if ok != (tt.status == 0) || version != tt.version || (tt.status != 0 && w.Code != tt.status) {
+			t.Errorf("If-Match %q: got %d, %v, status %d, want %d, status %d", tt.header, version, ok, w.Code, tt.version, tt.status)
+		}
+	}
+}
+
+// each format of a course has its own ETag, and a cached tag only validates
+// the format it was sent with
+func TestETagDependsOnFormat(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	handler := s.negotiationMiddleware(s.Handler)
+	course, err := s.Db.Create(context.Background(), models.CreateCourseParams{Name: "Go", Price: 10, Technology: []string{"Go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/courses/"+course.Id, nil)
+		r.Header.Set("Accept", accept)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	tags := map[string]string{}
+	for _, accept := range []string{"application/json", "application/xml", "application/yaml", "application/msgpack"} {
+		w := get(accept, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", accept, w.Code)
+		}
+		if !slices.Contains(w.Header().Values("Vary"), "Accept") {
+			t.Errorf("%s: Vary %q, want Accept", accept, w.Header().Values("Vary"))
+		}
+		tag := w.Header().Get("ETag")
+		if !strings.HasPrefix(tag, `"1-`) {
+			t.Errorf("%s: ETag %s, want version 1", accept, tag)
+		}
+		for other, otherTag := range tags {
+			if tag == otherTag {
+				t.Errorf("%s and %s share the ETag %s", accept, other, tag)
+			}
+		}
+		tags[accept] = tag
+	}
+
+	if w := get("application/xml", tags["application/xml"]); w.Code != http.StatusNotModified {
+		t.Errorf("xml revalidated with its own tag: status %d, want 304", w.Code)
+	}
+	if w := get("application/xml", "W/"+tags["application/xml"]); w.Code != http.StatusNotModified {
+		t.Errorf("xml revalidated with its tag marked weak: status %d, want 304", w.Code)
+	}
+	if w := get("application/xml", tags["application/json"]); w.Code != http.StatusOK {
+		t.Errorf("xml revalidated with the json tag: status %d, want 200", w.Code)
+	}
+}
+
+func TestIfMatchList(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	course := updatedCourse(t, s)
+	for header, want := range map[string]int{
+		`"1-json", "2-json"`: http.StatusOK,
+		`"1-json", "9-json"`: http.StatusPreconditionFailed,
+	} {
+		r := httptest.NewRequest(http.MethodPut, "/courses/"+course.Id, strings.NewReader(`{"name": "Go", "price": 15, "technology": ["Go"]}`))
+		r.Header.Set("If-Match", header)
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("If-Match %s: status %d, want %d: %s", header, w.Code, want, w.Body)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/course-api/internal/pkg/logging"
+)
+
+// HealthReport is the /readyz body
+type HealthReport struct {
+	// ready, not_ready or shutting_down
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+type HealthCheck struct {
+	// up or down
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// health tracks whether the server can take traffic
+type health struct {
+	connected atomic.Bool
+	draining  atomic.Bool
+}
+
+// first wait between connection attempts, doubled up to
+// Config.Database.ConnectMaxBackoff
+const connectInitialBackoff = 500 * time.Millisecond
+
+// connect pings the database until it answers, then runs s.Startup, and marks
+// the server ready. Failures are retried with jittered exponential backoff so
+// a database that comes up after the api does not crash loop it. It gives up
+// when ctx is cancelled.
+func (s *ApiServer) connect(ctx context.Context) {
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.pingDatabase(ctx)
+		if err == nil && s.Startup != nil {
+			err = s.Startup(ctx)
+		}
+		if err == nil {
+			s.health.connected.Store(true)
+			slog.Info("database connected", "attempt", attempt)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// up to 50% jitter so replicas do not retry in lockstep
+		wait := backoff/2 + rand.N(backoff/2+1)
+		slog.Warn("database not ready, retrying", "attempt", attempt, "retry_in", wait.String(), "err", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, s.Config.Database.ConnectMaxBackoff)
+	}
+}
+
+func (s *ApiServer) pingDatabase(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.Config.Database.HealthTimeout)
+	defer cancel()
+	return s.Db.Ping(ctx)
+}
+
+// healthz is the liveness probe, it only shows the process is serving
+func (s *ApiServer) healthz(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz is the readiness probe. It is 503 until the first connection
+// succeeded, while the database does not answer within the health timeout
+// and once shutdown has started. The probe needs no auth, so driver errors
+// are only logged; the body says which dependency is down, not why.
+func (s *ApiServer) readyz(w http.ResponseWriter, r *http.Request) {
+	report := HealthReport{Status: "ready", Checks: map[string]HealthCheck{}}
+
+	database := HealthCheck{Status: "up"}
+	if !s.health.connected.Load() {
+		// connect logs every failed attempt
+		database.Status = "down"
+		database.Error = "not connected yet"
+	} else {
+		start := time.Now()
+		err := s.pingDatabase(r.Context())
+		database.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("readiness check failed", "err", err)
+			database.Status = "down"
+			database.Error = "database unavailable"
+		}
+	}
+	report.Checks["database"] = database
+
+	status := http.StatusOK
+	switch {
+	case s.health.draining.Load():
+		report.Status = "shutting_down"
+		status = http.StatusServiceUnavailable
+	case database.Status != "up":
+		report.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, r, status, report)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/database"
+)
+
+// flakyStore fails its first pings
+type flakyStore struct {
+	database.Interface
+	failures atomic.Int32
+	pings    atomic.Int32
+}
+
+func (s *flakyStore) Ping(ctx context.Context) error {
+	if s.pings.Add(1) <= s.failures.Load() {
+		return errors.New("connection refused")
+	}
+	return s.Interface.Ping(ctx)
+}
+
+func readyz(t *testing.T, s *ApiServer) (int, HealthReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("/readyz body %s: %v", w.Body, err)
+	}
+	return w.Code, report
+}
+
+func TestReadyz(t *testing.T) {
+	s := authServer(t, false)
+	db := &flakyStore{}
+	db.Interface = s.Db
+	db.failures.Store(1)
+	s.Db = db
+
+	if code, report := readyz(t, s); code != http.StatusServiceUnavailable || report.Status != "not_ready" || report.Checks["database"].Status != "down" {
+		t.Errorf("before connecting: %d %+v, want 503 not_ready", code, report)
+	}
+
+	// the first ping fails, connect retries until the second one answers
+	s.connect(context.Background())
+	if got := db.pings.Load(); got != 2 {
+		t.Errorf("%d pings, want a retry after the failure", got)
+	}
+	if code, report := readyz(t, s); code != http.StatusOK || report.Status != "ready" || report.Checks["database"].Status != "up" {
+		t.Errorf("connected: %d %+v, want 200 ready", code, report)
+	}
+
+	// the database going away later is reported, not cached
+	db.failures.Store(100)
+	if code, report := readyz(t, s); code != http.StatusServiceUnavailable || report.Checks["database"].Error != "database unavailable" {
+		t.Errorf("database down: %d %+v, want 503 without the driver error", code, report)
+	}
+
+	db.failures.Store(0)
+	s.health.draining.Store(true)
+	if code, report := readyz(t, s); code != http.StatusServiceUnavailable || report.Status != "shutting_down" {
+		t.Errorf("draining: %d %+v, want 503 shutting_down", code, report)
+	}
+}
+
+func TestConnectRunsStartup(t *testing.T) {
+	s := authServer(t, false)
+	calls := 0
+	s.Startup = func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("migration locked")
+		}
+		return nil
+	}
+	s.connect(context.Background())
+	if calls != 2 || !s.health.connected.Load() {
+		t.Errorf("startup ran %d times, connected %v, want a retry and ready", calls, s.health.connected.Load())
+	}
+}
+
+func TestConnectStopsOnCancel(t *testing.T) {
+	s := authServer(t, false)
+	db := &flakyStore{}
+	db.Interface = s.Db
+	db.failures.Store(1 << 30)
+	s.Db = db
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.connect(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("connect kept retrying after its context was cancelled")
+	}
+	if s.health.connected.Load() {
+		t.Error("marked connected without a successful ping")
+	}
+	if _, report := readyz(t, s); report.Checks["database"].Error != "not connected yet" {
+		t.Errorf("readyz error %q, want not connected yet without the driver error", report.Checks["database"].Error)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	s := authServer(t, true)
+	s.SetUpRoutes()
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/healthz: %d, want 200 without credentials", w.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/logging"
+)
+
+// captureLogs sends the default logger to a json buffer for the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(logging.New(&buf, "debug", "json"))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := captureLogs(t)
+	handler := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Warn("from the handler")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/courses?limit=5", nil))
+
+	id := w.Header().Get(requestIDHeader)
+	if id == "" {
+		t.Fatal("no X-Request-ID on the response")
+	}
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("%d log lines, want the handler's and the request line", len(lines))
+	}
+	for _, line := range lines {
+		if line["request_id"] != id {
+			t.Errorf("log line %v does not carry request id %s", line, id)
+		}
+	}
+	request := lines[1]
+	want := map[string]any{"msg": "request", "method": "GET", "path": "/courses", "query": "limit=5", "status": float64(418), "bytes": float64(15)}
+	for k, v := range want {
+		if request[k] != v {
+			t.Errorf("request log %s = %v, want %v", k, request[k], v)
+		}
+	}
+}
+
+func TestLoggingLevelFollowsStatus(t *testing.T) {
+	for status, level := range map[int]string{http.StatusOK: "INFO", http.StatusNotFound: "INFO", http.StatusBadGateway: "ERROR"} {
+		buf := captureLogs(t)
+		handler := loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := logLines(t, buf)[0]["level"]; got != level {
+			t.Errorf("status %d logged at %v, want %s", status, got, level)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"none", "", false},
+		{"propagated", "abc-123", true},
+		{"with spaces", "abc 123", false},
+		{"control characters", "abc\x01", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.incoming != "" {
+			r.Header.Set(requestIDHeader, tt.incoming)
+		}
+		got := requestID(r)
+		if got == "" || (got == tt.incoming) != tt.kept {
+			t.Errorf("%s: request id %q for incoming %q", tt.name, got, tt.incoming)
+		}
+	}
+}
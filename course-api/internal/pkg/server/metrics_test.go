@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/tracing"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func scrape(t *testing.T, s *ApiServer) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/metrics: %d %s", w.Code, w.Body)
+	}
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = false
+	cfg.Metrics.Enabled = true
+	s := NewApiServer(cfg, mux.NewRouter(), database.NewMemoryStore())
+	s.SetUpRoutes()
+	s.Handler.Use(s.metricsMiddleware)
+
+	for _, path := range []string{"/courses/" + uuid.NewString(), "/courses/" + uuid.NewString(), "/nope", "/courses"} {
+		s.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest("BREW", "/courses", nil))
+
+	out := scrape(t, s)
+	for _, want := range []string{
+		// one series per route template, not per id
+		`course_api_http_requests_total{method="GET",route="/courses/{id}",status="404"} 2`,
+		`course_api_http_requests_total{method="GET",route="/courses",status="200"} 1`,
+		`course_api_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`course_api_http_requests_total{method="OTHER",route="unmatched",status="405"} 1`,
+		`course_api_http_request_duration_seconds_count{method="GET",route="/courses/{id}",status="404"} 2`,
+		`course_api_db_operation_errors_total{backend="memory",kind="not_found",operation="get_by_id"} 2`,
+		`course_api_db_operation_duration_seconds_count{backend="memory",operation="get_all"} 1`,
+		"course_api_build_info{",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/metrics does not contain %s", want)
+		}
+	}
+}
+
+func TestMetricsOff(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = false
+	cfg.Metrics.Enabled = false
+	s := NewApiServer(cfg, mux.NewRouter(), database.NewMemoryStore())
+	s.SetUpRoutes()
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("/metrics with metrics off: %d, want 404", w.Code)
+	}
+}
+
+// tracing wraps the store too, the metrics still see the backend and its pool
+func TestMetricsWithTracing(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := database.NewSQLiteSession(ctx, filepath.Join(t.TempDir(), "test.db"), database.PoolConfig{MaxOpenConns: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	migrator, err := sqlite.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Auth.Enabled = false
+	cfg.Metrics.Enabled = true
+	cfg.Tracing.Exporter = tracing.Stdout
+	s := NewApiServer(cfg, mux.NewRouter(), sqlite)
+	s.SetUpRoutes()
+	if got := database.BackendName(s.Db); got != "sqlite" {
+		t.Errorf("instrumented store reports backend %q, want sqlite", got)
+	}
+	s.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/courses", nil))
+
+	out := scrape(t, s)
+	for _, want := range []string{
+		`course_api_db_pool_max_open_connections{backend="sqlite"} 3`,
+		`course_api_db_operation_duration_seconds_count{backend="sqlite",operation="get_all"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("/metrics does not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/codec"
+	"github.com/course-api/internal/pkg/models"
+)
+
+// a course sent in one format can be read back in every other
+func TestNegotiatedFormats(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	handler := s.negotiationMiddleware(s.Handler)
+	for _, in := range codec.All {
+		body, err := in.Marshal(models.CreateCourseParams{Name: "Go in " + in.Name(), Price: 10, Technology: []string{"Go"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/course", bytes.NewReader(body))
+		r.Header.Set("Content-Type", in.MediaType)
+		r.Header.Set("Accept", in.MediaType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusCreated || w.Header().Get("Content-Type") != in.MediaType {
+			t.Fatalf("%s: create got %d %s: %s", in.Name(), w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+		var created models.Course
+		if err := in.Decode(w.Body, &created); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, out := range codec.All {
+			r := httptest.NewRequest(http.MethodGet, "/courses/"+created.Id, nil)
+			r.Header.Set("Accept", out.MediaType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			var got models.Course
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != out.MediaType || out.Decode(w.Body, &got) != nil {
+				t.Errorf("%s as %s: %d %s", in.Name(), out.Name(), w.Code, w.Header().Get("Content-Type"))
+				continue
+			}
+			if !models.SameContent(got, created) {
+				t.Errorf("%s as %s: got %+v, want %+v", in.Name(), out.Name(), got, created)
+			}
+		}
+	}
+}
+
+func TestUnsupportedFormats(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	handler := s.negotiationMiddleware(s.Handler)
+
+	r := httptest.NewRequest(http.MethodGet, "/courses", nil)
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable || w.Header().Get("Content-Type") != codec.JSON.ProblemType {
+		t.Errorf("Accept image/png: %d %s, want a 406 json problem", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/course", strings.NewReader("name=Go"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("form body: %d, want 415", w.Code)
+	}
+	if page, err := s.Db.GetAll(context.Background(), models.ListCoursesParams{Limit: 1}); err != nil || page.Total != 0 {
+		t.Errorf("%d courses after rejected requests, %v", page.Total, err)
+	}
+
+	// the openapi document is json whatever is asked for
+	r = httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	r.Header.Set("Accept", "application/xml")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("/openapi.json with Accept xml: %d, want 200", w.Code)
+	}
+}
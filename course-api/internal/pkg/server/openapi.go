@@ -0,0 +1,543 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/codec"
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/models"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// operation documents one method + path template registered in SetUpRoutes.
+// TestEveryRouteIsDocumented fails when a route has no entry here.
+type operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Params      []param
+	Body        *body
+	Responses   []response
+	// minimum role when auth is enabled, empty for public routes
+	Role string
+	// nil means the route is always registered
+	Enabled func(cfg config.Config) bool
+}
+
+// the admin routes are only served with auth enabled, there is no telling
+// admins apart otherwise
+func adminRoutes(cfg config.Config) bool {
+	return cfg.Auth.Enabled
+}
+
+type param struct {
+	Name        string
+	In          string
+	Description string
+	Schema      map[string]any
+	Required    bool
+}
+
+type body struct {
+	// content type -> go value (reflected) or raw schema map
+	Content  map[string]any
+	Required bool
+}
+
+type response struct {
+	Status      int
+	Description string
+	ContentType string
+	Schema      any
+	Headers     map[string]string
+}
+
+var idParam = param{Name: "id", In: "path", Required: true, Description: "course id",
+	Schema: map[string]any{"type": "string", "format": "uuid"}}
+
+var apiKeyIDParam = param{Name: "id", In: "path", Required: true, Description: "api key id",
+	Schema: map[string]any{"type": "string", "format": "uuid"}}
+
+var ifMatchParam = param{Name: "If-Match", In: "header", Required: true, Description: `ETag of the version being changed, "*" for any`,
+	Schema: map[string]any{"type": "string"}}
+
+var includeDeletedParam = param{Name: "include_deleted", In: "query", Description: "also return deleted courses, admin only (403 otherwise or with auth disabled)",
+	Schema: map[string]any{"type": "boolean", "default": false}}
+
+var etagHeader = map[string]string{"ETag": `version and format of the course, e.g. "3-json", for If-Match and If-None-Match`}
+
+var listParams = []param{
+	{Name: "limit", In: "query", Description: "page size", Schema: map[string]any{"type": "integer", "minimum": 1, "maximum": models.MaxPageLimit, "default": models.DefaultPageLimit}},
+	{Name: "offset", In: "query", Description: "rows to skip, cannot be combined with cursor", Schema: map[string]any{"type": "integer", "minimum": 0}},
+	{Name: "cursor", In: "query", Description: "opaque cursor from pagination.next_cursor", Schema: map[string]any{"type": "string"}},
+	{Name: "technology", In: "query", Description: "only courses using this technology", Schema: map[string]any{"type": "string"}},
+	{Name: "name", In: "query", Description: "course name prefix", Schema: map[string]any{"type": "string"}},
+	{Name: "min_price", In: "query", Schema: map[string]any{"type": "number", "minimum": 0}},
+	{Name: "max_price", In: "query", Schema: map[string]any{"type": "number", "minimum": 0}},
+	{Name: "sort", In: "query", Description: `comma separated fields, "-" for descending, e.g. price,-name`, Schema: map[string]any{"type": "string", "default": "name"}},
+	includeDeletedParam,
+}
+
+var jsonPatchSchema = map[string]any{
+	"type": "array",
+	"items": map[string]any{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": map[string]any{
+			"op":    map[string]any{"type": "string", "enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+			"path":  map[string]any{"type": "string"},
+			"from":  map[string]any{"type": "string"},
+			"value": map[string]any{},
+		},
+	},
+}
+
+func problemResponse(status int, description string) response {
+	return response{Status: status, Description: description, ContentType: problemContentType, Schema: Problem{}}
+}
+
+var operations = []operation{
+	{Method: "GET", Path: "/", Summary: "Welcome page", Tag: "misc",
+		Responses: []response{{Status: 200, Description: "html welcome page", ContentType: "text/html"}}},
+	{Method: "GET", Path: "/courses", Role: auth.RoleReader, Summary: "List courses", Tag: "courses", Params: listParams,
+		Description: "Paginated with limit/offset or an opaque cursor. The Link header carries first, prev and next urls.",
+		Responses: []response{
+			{Status: 200, Description: "a page of courses", ContentType: "application/json", Schema: models.CourseList{},
+				Headers: map[string]string{"Link": "RFC 8288 pagination links"}},
+			problemResponse(400, "invalid query parameters"),
+		}},
+	{Method: "POST", Path: "/course", Role: auth.RoleEditor, Summary: "Create a course", Tag: "courses",
+		Body: &body{Required: true, Content: map[string]any{"application/json": models.CreateCourseParams{}}},
+		Responses: []response{
+			{Status: 201, Description: "the created course", ContentType: "application/json", Schema: models.Course{}, Headers: etagHeader},
+			problemResponse(400, "missing or malformed body"),
+			problemResponse(409, "course already exists"),
+			problemResponse(422, "invalid fields"),
+		}},
+	{Method: "POST", Path: "/courses/bulk", Role: auth.RoleEditor, Summary: "Create many courses", Tag: "courses",
+		Description: "mode=atomic (default) creates every course in one transaction or none, any invalid course fails the request. " +
+			"mode=best_effort creates the courses it can and reports each one with the status POST /course would have given it. " +
+			"The number of courses per request is limited by BULK_MAX_BATCH_SIZE.",
+		Params: []param{{Name: "mode", In: "query", Schema: map[string]any{"type": "string", "enum": []string{models.BulkAtomic, models.BulkBestEffort}, "default": models.BulkAtomic}}},
+		Body:   &body{Required: true, Content: map[string]any{"application/json": []models.CreateCourseParams{}}},
+		Responses: []response{
+			{Status: 201, Description: "atomic: every course was created", ContentType: "application/json", Schema: models.BulkResponse{}},
+			{Status: 207, Description: "best_effort: a result per course", ContentType: "application/json", Schema: models.BulkResponse{}},
+			problemResponse(400, "invalid mode, missing, empty or malformed body"),
+			problemResponse(409, "atomic: a course conflicts with an existing record, the error is keyed by [index]"),
+			problemResponse(413, "too many courses"),
+			problemResponse(422, "atomic: some courses are invalid or rejected by the database, errors are keyed by [index].field or [index]"),
+		}},
+	{Method: "GET", Path: "/courses/export", Role: auth.RoleReader, Summary: "Export courses as csv", Tag: "courses",
+		Description: "Every course sorted by name, one row per course with the columns " + strings.Join(models.CSVColumns, ",") +
+			" and technologies joined with " + models.TechnologySeparator + ". Cells that would start a spreadsheet formula are prefixed with '.",
+		Params: []param{{Name: "format", In: "query", Schema: map[string]any{"type": "string", "enum": []string{"csv"}, "default": "csv"}}},
+		Responses: []response{
+			{Status: 200, Description: "the catalog as csv", ContentType: "text/csv", Schema: map[string]any{"type": "string"},
+				Headers: map[string]string{"Content-Disposition": "attachment; filename=\"courses.csv\""}},
+			problemResponse(400, "unsupported format"),
+		}},
+	{Method: "POST", Path: "/courses/import", Role: auth.RoleEditor, Summary: "Import courses from csv", Tag: "courses",
+		Description: "Takes a file in the export format, the header row may list the columns in any order and leave out id. " +
+			"Rows with the id of a course replace it, the others are created. Everything is applied in one transaction, " +
+			"any invalid row fails the import with an error per problem and its line number. " +
+			"dry_run=true checks the file and counts what would change without applying anything. " +
+			"The number of rows per request is limited by IMPORT_MAX_ROWS.",
+		Params: []param{{Name: "dry_run", In: "query", Schema: map[string]any{"type": "boolean", "default": false}}},
+		Body: &body{Required: true, Content: map[string]any{
+			"text/csv": map[string]any{"type": "string"},
+			"multipart/form-data": map[string]any{"type": "object", "required": []string{"file"},
+				"properties": map[string]any{"file": map[string]any{"type": "string", "format": "binary"}}},
+		}},
+		Responses: []response{
+			{Status: 200, Description: "what was imported, or would be with dry_run", ContentType: "application/json", Schema: models.ImportSummary{}},
+			problemResponse(400, "invalid dry_run, unreadable csv or no rows"),
+			problemResponse(413, "too many rows"),
+			problemResponse(415, "not text/csv or multipart/form-data"),
+			problemResponse(422, "invalid header or rows, errors carry the csv line"),
+		}},
+	{Method: "GET", Path: "/courses/search", Role: auth.RoleReader, Summary: "Search courses", Tag: "courses",
+		Description: "Matches the words of q against course names and technologies, a course needs any one of them. " +
+			"Hits are ranked by relevance, name matches count more and courses matching more or rarer words rank higher. " +
+			"Highlights repeat the matching fields with the matched words in <mark> tags, html escaped.",
+		Params: []param{
+			{Name: "q", In: "query", Required: true, Description: "search words, at most " + strconv.Itoa(models.MaxSearchTerms), Schema: map[string]any{"type": "string"}},
+			listParams[0],
+			{Name: "offset", In: "query", Description: "hits to skip", Schema: map[string]any{"type": "integer", "minimum": 0}},
+		},
+		Responses: []response{
+			{Status: 200, Description: "a page of hits, best first", ContentType: "application/json", Schema: models.SearchResults{}},
+			problemResponse(400, "missing q or invalid paging"),
+		}},
+	{Method: "GET", Path: "/courses/{id}", Role: auth.RoleReader, Summary: "Get a course", Tag: "courses",
+		Params: []param{idParam, includeDeletedParam, {Name: "If-None-Match", In: "header", Description: "ETag the client has cached", Schema: map[string]any{"type": "string"}}},
+		Responses: []response{
+			{Status: 200, Description: "the course", ContentType: "application/json", Schema: models.Course{}, Headers: etagHeader},
+			{Status: 304, Description: "the cached version is current", Headers: etagHeader},
+			problemResponse(400, "invalid id"),
+			problemResponse(404, "course not found"),
+		}},
+	{Method: "PUT", Path: "/courses/{id}", Role: auth.RoleEditor, Summary: "Replace a course", Tag: "courses", Params: []param{idParam, ifMatchParam},
+		Description: "Only replaces the version named by If-Match, so concurrent editors cannot overwrite each other.",
+		Body:        &body{Required: true, Content: map[string]any{"application/json": models.UpdateCourseParams{}}},
+		Responses: []response{
+			{Status: 200, Description: "the updated course", ContentType: "application/json", Schema: models.Course{}, Headers: etagHeader},
+			problemResponse(400, "invalid id or body"),
+			problemResponse(404, "course not found"),
+			problemResponse(412, "the course is no longer at the If-Match version"),
+			problemResponse(422, "invalid fields"),
+			problemResponse(428, "If-Match is missing"),
+		}},
+	{Method: "PATCH", Path: "/courses/{id}", Role: auth.RoleEditor, Summary: "Partially update a course", Tag: "courses",
+		Params:      []param{idParam, {Name: "If-Match", In: "header", Description: "only patch this version, ETag from GET", Schema: map[string]any{"type": "string"}}},
+		Description: "Accepts JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902). Only the sent fields change.",
+		Enabled:     func(cfg config.Config) bool { return cfg.Features.Patch },
+		Body: &body{Required: true, Content: map[string]any{
+			"application/merge-patch+json": map[string]any{"type": "object"},
+			"application/json-patch+json":  jsonPatchSchema,
+		}},
+		Responses: []response{
+			{Status: 200, Description: "the patched course", ContentType: "application/json", Schema: models.Course{}, Headers: etagHeader},
+			problemResponse(400, "invalid id or patch document"),
+			problemResponse(404, "course not found"),
+			problemResponse(412, "the course is no longer at the If-Match version"),
+			problemResponse(415, "unsupported patch format"),
+			problemResponse(422, "patch cannot be applied"),
+		}},
+	{Method: "DELETE", Path: "/courses/{id}", Role: auth.RoleEditor, Summary: "Delete a course", Tag: "courses", Params: []param{idParam, ifMatchParam},
+		Description: "Marks the course deleted, it is hidden until restored and only removed for good by DELETE /admin/courses/{id}.",
+		Responses: []response{
+			{Status: 204, Description: "deleted"},
+			problemResponse(400, "invalid id"),
+			problemResponse(404, "course not found"),
+			problemResponse(412, "the course is no longer at the If-Match version"),
+			problemResponse(428, "If-Match is missing"),
+		}},
+	{Method: "POST", Path: "/courses/{id}/restore", Role: auth.RoleEditor, Summary: "Restore a deleted course", Tag: "courses",
+		Params: []param{idParam, {Name: "If-Match", In: "header", Description: "only restore this version, ETag from GET with include_deleted", Schema: map[string]any{"type": "string"}}},
+		Responses: []response{
+			{Status: 200, Description: "the restored course", ContentType: "application/json", Schema: models.Course{}, Headers: etagHeader},
+			problemResponse(400, "invalid id"),
+			problemResponse(404, "course not found"),
+			problemResponse(409, "course is not deleted"),
+			problemResponse(412, "the course is no longer at the If-Match version"),
+		}},
+	{Method: "GET", Path: "/technologies", Role: auth.RoleReader, Summary: "List technologies", Tag: "technologies",
+		Description: "Every technology used by at least one course, sorted by name, with the number of courses using it.",
+		Responses: []response{
+			{Status: 200, Description: "the technologies", ContentType: "application/json", Schema: models.TechnologyList{}},
+		}},
+	{Method: "GET", Path: "/admin/api-keys", Role: auth.RoleAdmin, Summary: "List api keys", Tag: "admin",
+		Enabled:     adminRoutes,
+		Description: "Revoked and expired keys are listed too. The plaintext is never shown again after creation.",
+		Responses: []response{
+			{Status: 200, Description: "all api keys", ContentType: "application/json", Schema: []models.APIKey{}},
+		}},
+	{Method: "POST", Path: "/admin/api-keys", Role: auth.RoleAdmin, Summary: "Create an api key", Tag: "admin",
+		Enabled:     adminRoutes,
+		Description: "The response holds the plaintext key, store it now, only its hash is kept. Send it in the X-API-Key header.",
+		Body:        &body{Required: true, Content: map[string]any{"application/json": models.CreateAPIKeyParams{}}},
+		Responses: []response{
+			{Status: 201, Description: "the key with its plaintext", ContentType: "application/json", Schema: models.NewAPIKey{}},
+			problemResponse(400, "missing or malformed body"),
+			problemResponse(422, "invalid fields"),
+		}},
+	{Method: "POST", Path: "/admin/api-keys/{id}/rotate", Role: auth.RoleAdmin, Summary: "Rotate an api key", Tag: "admin",
+		Enabled:     adminRoutes,
+		Params:      []param{apiKeyIDParam},
+		Description: "Issues a new plaintext for the key, the old one stops working immediately.",
+		Responses: []response{
+			{Status: 200, Description: "the key with its new plaintext", ContentType: "application/json", Schema: models.NewAPIKey{}},
+			problemResponse(400, "invalid id"),
+			problemResponse(404, "api key not found or revoked"),
+		}},
+	{Method: "DELETE", Path: "/admin/api-keys/{id}", Role: auth.RoleAdmin, Summary: "Revoke an api key", Tag: "admin",
+		Enabled: adminRoutes,
+		Params:  []param{apiKeyIDParam},
+		Responses: []response{
+			{Status: 204, Description: "revoked"},
+			problemResponse(400, "invalid id"),
+			problemResponse(404, "api key not found"),
+		}},
+	{Method: "DELETE", Path: "/admin/courses/{id}", Role: auth.RoleAdmin, Summary: "Purge a deleted course", Tag: "admin",
+		Enabled:     adminRoutes,
+		Description: "Removes a course for good. Only deleted courses can be purged.",
+		Params:      []param{idParam},
+		Responses: []response{
+			{Status: 204, Description: "purged"},
+			problemResponse(400, "invalid id"),
+			problemResponse(404, "course not found"),
+			problemResponse(409, "course is not deleted"),
+		}},
+	{Method: "GET", Path: "/metrics", Summary: "Prometheus metrics", Tag: "ops",
+		Description: "HTTP and database latency, error counts, connection pool stats and build info in the Prometheus text format.",
+		Enabled:     func(cfg config.Config) bool { return cfg.Metrics.Enabled },
+		Responses:   []response{{Status: 200, Description: "metrics", ContentType: "text/plain"}}},
+	{Method: "GET", Path: "/healthz", Summary: "Liveness probe", Tag: "ops",
+		Description: "Always 200 while the process serves requests, it does not touch the database.",
+		Responses: []response{{Status: 200, Description: "alive", ContentType: "application/json",
+			Schema: map[string]any{"type": "object", "properties": map[string]any{"status": map[string]any{"type": "string"}}}}}},
+	{Method: "GET", Path: "/readyz", Summary: "Readiness probe", Tag: "ops",
+		Description: "Pings the database with a timeout and reports the status of each dependency. Not ready until the first connection on startup succeeded and again once shutdown begins.",
+		Responses: []response{
+			{Status: 200, Description: "ready for traffic", ContentType: "application/json", Schema: HealthReport{}},
+			{Status: 503, Description: "a dependency is down or the server is shutting down", ContentType: "application/json", Schema: HealthReport{}},
+		}},
+	{Method: "GET", Path: "/openapi.json", Summary: "This OpenAPI document", Tag: "docs",
+		Responses: []response{{Status: 200, Description: "OpenAPI 3.1 document", ContentType: "application/json", Schema: map[string]any{"type": "object"}}}},
+	{Method: "GET", Path: "/docs", Summary: "Interactive api docs", Tag: "docs",
+		Responses: []response{{Status: 200, Description: "html docs page", ContentType: "text/html"}}},
+}
+
+// openAPISpec builds the document for the routes enabled in the config
+func (s *ApiServer) openAPISpec() map[string]any {
+	components := map[string]any{}
+	paths := map[string]any{}
+	for _, op := range operations {
+		if op.Enabled != nil && !op.Enabled(s.Config) {
+			continue
+		}
+		item, ok := paths[op.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.spec(components, s.Config)
+	}
+	componentsSpec := map[string]any{"schemas": components}
+	if s.Config.Auth.Enabled {
+		componentsSpec["securitySchemes"] = map[string]any{
+			"bearerAuth": map[string]any{
+				"type":         "http",
+				"scheme":       "bearer",
+				"bearerFormat": "JWT",
+				"description":  "HS256 or RS256 token with a roles claim: reader, editor or admin",
+			},
+			"apiKeyAuth": map[string]any{
+				"type":        "apiKey",
+				"in":          "header",
+				"name":        apiKeyHeader,
+				"description": "key from POST /admin/api-keys, its scopes act as roles",
+			},
+		}
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Course API",
+			"version":     "1.0.0",
+			"description": "CRUD api for the course catalog. Bodies are json, xml, yaml or msgpack as negotiated with Accept and Content-Type. Errors are RFC 7807 problem documents in the same format.",
+		},
+		"paths":      paths,
+		"components": componentsSpec,
+	}
+}
+
+func (op operation) spec(components map[string]any, cfg config.Config) map[string]any {
+	secured := cfg.Auth.Enabled && op.Role != ""
+	if secured {
+		op.Responses = append(op.Responses,
+			problemResponse(401, "missing, invalid or expired credentials"),
+			problemResponse(403, "token lacks the "+op.Role+" role"))
+	}
+	if !fixedFormat(op.Path) {
+		op.Responses = append(op.Responses, problemResponse(406, "accepts none of "+codec.MediaTypes()))
+	}
+	if op.Body != nil && op.Body.Content[codec.JSON.MediaType] != nil {
+		op.Responses = append(op.Responses, problemResponse(415, "body is none of "+codec.MediaTypes()))
+	}
+	if cfg.RateLimit.Enabled && !unlimited(op.Path) {
+		limited := problemResponse(429, "rate limit exceeded")
+		limited.Headers = map[string]string{"Retry-After": "seconds until a request is allowed again"}
+		op.Responses = append(op.Responses, limited)
+	}
+	out := map[string]any{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+		"tags":        []string{op.Tag},
+	}
+	if op.Description != "" {
+		out["description"] = op.Description
+	}
+	if len(op.Params) > 0 {
+		var params []map[string]any
+		for _, p := range op.Params {
+			spec := map[string]any{"name": p.Name, "in": p.In, "required": p.Required, "schema": p.Schema}
+			if p.Description != "" {
+				spec["description"] = p.Description
+			}
+			params = append(params, spec)
+		}
+		out["parameters"] = params
+	}
+	if op.Body != nil {
+		content := map[string]any{}
+		for contentType, v := range op.Body.Content {
+			for _, contentType := range negotiated(contentType) {
+				content[contentType] = map[string]any{"schema": schemaOf(v, components)}
+			}
+		}
+		out["requestBody"] = map[string]any{"required": op.Body.Required, "content": content}
+	}
+	responses := map[string]any{}
+	for _, r := range op.Responses {
+		spec := map[string]any{"description": r.Description}
+		if r.ContentType != "" {
+			media := map[string]any{}
+			if r.Schema != nil {
+				media["schema"] = schemaOf(r.Schema, components)
+			}
+			content := map[string]any{}
+			contentTypes := []string{r.ContentType}
+			if r.ContentType == problemContentType || !fixedFormat(op.Path) {
+				contentTypes = negotiated(r.ContentType)
+			}
+			for _, contentType := range contentTypes {
+				content[contentType] = media
+			}
+			spec["content"] = content
+		}
+		if len(r.Headers) > 0 {
+			headers := map[string]any{}
+			for name, description := range r.Headers {
+				headers[name] = map[string]any{"description": description, "schema": map[string]any{"type": "string"}}
+			}
+			spec["headers"] = headers
+		}
+		responses[strconv.Itoa(r.Status)] = spec
+	}
+	responses["default"] = map[string]any{
+		"description": "unexpected error",
+		"content":     map[string]any{problemContentType: map[string]any{"schema": schemaOf(Problem{}, components)}},
+	}
+	out["responses"] = responses
+	if secured {
+		out["security"] = []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}}
+	}
+	return out
+}
+
+// negotiated lists the media types a json body or problem can also be sent
+// as, any other content type is returned alone
+func negotiated(contentType string) []string {
+	var types []string
+	for _, c := range codec.All {
+		switch contentType {
+		case c.MediaType, codec.JSON.MediaType:
+			types = append(types, c.MediaType)
+		case c.ProblemType, codec.JSON.ProblemType:
+			types = append(types, c.ProblemType)
+		}
+	}
+	if len(types) == 0 {
+		return []string{contentType}
+	}
+	return types
+}
+
+// operationID turns "GET /courses/{id}" into "getCoursesId"
+func operationID(op operation) string {
+	id := strings.ToLower(op.Method)
+	for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '.' || r == '-'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	if op.Path == "/" {
+		id += "Root"
+	}
+	return id
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns a raw schema map as is, otherwise reflects a json schema
+// from the value's type. Named structs go into components and are referenced.
+func schemaOf(v any, components map[string]any) map[string]any {
+	if raw, ok := v.(map[string]any); ok {
+		return raw
+	}
+	return schemaForType(reflect.TypeOf(v), components)
+}
+
+func schemaForType(t reflect.Type, components map[string]any) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaForType(t.Elem(), components)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaForType(t.Elem(), components)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaForType(t.Elem(), components)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := components[t.Name()]; ok {
+			return ref
+		}
+		// placeholder first so self referencing types terminate
+		components[t.Name()] = map[string]any{}
+		properties := map[string]any{}
+		var required []string
+		addProperties(t, properties, &required, components)
+		sort.Strings(required)
+		components[t.Name()] = map[string]any{"type": "object", "properties": properties, "required": required}
+		return ref
+	default:
+		return map[string]any{}
+	}
+}
+
+// addProperties adds the json fields of struct t, embedded structs are
+// flattened the way encoding/json does
+func addProperties(t reflect.Type, properties map[string]any, required *[]string, components map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addProperties(f.Type, properties, required, components)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = schemaForType(f.Type, components)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func (s *ApiServer) showOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.spec)
+}
+
+func (s *ApiServer) showDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
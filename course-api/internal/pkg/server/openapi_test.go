@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// TestEveryRouteIsDocumented fails when a route is registered in SetUpRoutes
+// without a matching entry in operations, or the other way round.
+func TestEveryRouteIsDocumented(t *testing.T) {
+	withoutAuth := config.Default()
+	withoutAuth.Auth.Enabled = false
+	for _, cfg := range []config.Config{config.Default(), withoutAuth} {
+		checkRoutesDocumented(t, cfg)
+	}
+}
+
+func checkRoutesDocumented(t *testing.T, cfg config.Config) {
+	t.Helper()
+	s := NewApiServer(cfg, mux.NewRouter(), database.NewMemoryStore())
+	s.SetUpRoutes()
+	paths := s.spec["paths"].(map[string]any)
+
+	registered := map[string]bool{}
+	err := s.Handler.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("route %s has no methods", path)
+			return nil
+		}
+		for _, method := range methods {
+			method = strings.ToLower(method)
+			registered[method+" "+path] = true
+			item, _ := paths[path].(map[string]any)
+			if _, ok := item[method]; !ok {
+				t.Errorf("%s %s is registered but missing from the OpenAPI spec", strings.ToUpper(method), path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, item := range paths {
+		for method := range item.(map[string]any) {
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is in the OpenAPI spec but not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestAdminRoutesNeedAuth(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	for _, route := range [][2]string{
+		{http.MethodGet, "/admin/api-keys"},
+		{http.MethodPost, "/admin/api-keys"},
+		{http.MethodDelete, "/admin/courses/" + uuid.NewString()},
+	} {
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, httptest.NewRequest(route[0], route[1], nil))
+		if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s with auth disabled: status %d, want it not to be served", route[0], route[1], w.Code)
+		}
+	}
+	if _, ok := s.spec["paths"].(map[string]any)["/admin/api-keys"]; ok {
+		t.Error("/admin/api-keys is documented with auth disabled")
+	}
+}
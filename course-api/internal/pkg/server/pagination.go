@@ -0,0 +1,162 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/course-api/internal/pkg/models"
+	"github.com/course-api/internal/pkg/search"
+)
+
+// parseListParams reads limit/offset/cursor, filters and sort from the query string.
+// Invalid values come back as a models.ValidationError.
+func parseListParams(query url.Values) (models.ListCoursesParams, error) {
+	params := models.ListCoursesParams{
+		Limit:      models.DefaultPageLimit,
+		Technology: query.Get("technology"),
+		NamePrefix: query.Get("name"),
+		Sort:       []models.SortField{{Field: "name"}},
+	}
+	var err error
+	if val := query.Get("limit"); val != "" {
+		params.Limit, err = strconv.Atoi(val)
+		if err != nil || params.Limit < 1 || params.Limit > models.MaxPageLimit {
+			return params, models.NewFieldError("limit", fmt.Sprintf("must be between 1 and %d", models.MaxPageLimit))
+		}
+	}
+	if val := query.Get("offset"); val != "" {
+		params.Offset, err = strconv.Atoi(val)
+		if err != nil || params.Offset < 0 {
+			return params, models.NewFieldError("offset", "must be a positive number")
+		}
+	}
+	if params.MinPrice, err = parsePrice(query, "min_price"); err != nil {
+		return params, err
+	}
+	if params.MaxPrice, err = parsePrice(query, "max_price"); err != nil {
+		return params, err
+	}
+	if params.MinPrice != nil && params.MaxPrice != nil && *params.MinPrice > *params.MaxPrice {
+		return params, models.NewFieldError("min_price", "cannot be greater than max_price")
+	}
+	if val := query.Get("sort"); val != "" {
+		sort, err := models.ParseSort(val)
+		if err != nil {
+			return params, models.NewFieldError("sort", err.Error())
+		}
+		if len(sort) > 0 {
+			params.Sort = sort
+		}
+	}
+	if val := query.Get("cursor"); val != "" {
+		if query.Has("offset") {
+			return params, models.NewFieldError("cursor", "cannot be used together with offset")
+		}
+		cursor, err := models.DecodeCursor(val)
+		if err != nil {
+			return params, models.NewFieldError("cursor", err.Error())
+		}
+		// a cursor only makes sense for the sort it was created with
+		if cursor.Sort != models.FormatSort(params.Sort) {
+			return params, models.NewFieldError("cursor", "does not match the requested sort")
+		}
+		params.Cursor = &cursor
+	}
+	if params.IncludeDeleted, err = parseBool(query, "include_deleted"); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// parseSearchParams reads q, limit and offset for GET /courses/search
+func parseSearchParams(query url.Values) (models.SearchParams, error) {
+	params := models.SearchParams{
+		Terms: search.Terms(query.Get("q")),
+		Limit: models.DefaultPageLimit,
+	}
+	if len(params.Terms) == 0 {
+		return params, models.NewFieldError("q", "must contain at least one word")
+	}
+	if len(params.Terms) > models.MaxSearchTerms {
+		return params, models.NewFieldError("q", fmt.Sprintf("cannot have more than %d words", models.MaxSearchTerms))
+	}
+	var err error
+	if val := query.Get("limit"); val != "" {
+		params.Limit, err = strconv.Atoi(val)
+		if err != nil || params.Limit < 1 || params.Limit > models.MaxPageLimit {
+			return params, models.NewFieldError("limit", fmt.Sprintf("must be between 1 and %d", models.MaxPageLimit))
+		}
+	}
+	if val := query.Get("offset"); val != "" {
+		params.Offset, err = strconv.Atoi(val)
+		if err != nil || params.Offset < 0 {
+			return params, models.NewFieldError("offset", "must be a positive number")
+		}
+	}
+	return params, nil
+}
+
+func parsePrice(query url.Values, key string) (*float64, error) {
+	val := query.Get(key)
+	if val == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseFloat(val, 64)
+	if err != nil || price < 0 {
+		return nil, models.NewFieldError(key, "must be a positive number")
+	}
+	return &price, nil
+}
+
+func parseBool(query url.Values, key string) (bool, error) {
+	val := query.Get(key)
+	if val == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, models.NewFieldError(key, "must be true or false")
+	}
+	return b, nil
+}
+
+func writeQueryError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr models.ValidationError
+	errors.As(err, &validationErr)
+	writeProblem(w, r, http.StatusBadRequest, "invalid query parameters", validationErr...)
+}
+
+// setLinkHeader adds RFC 8288 first/prev/next links for the page
+func setLinkHeader(w http.ResponseWriter, r *http.Request, params models.ListCoursesParams, page models.CoursePage) {
+	link := func(rel string, set map[string]string) string {
+		u := *r.URL
+		query := u.Query()
+		query.Del("cursor")
+		query.Del("offset")
+		for k, v := range set {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+	}
+	links := []string{link("first", nil)}
+	if params.Cursor == nil && params.Offset > 0 {
+		prev := params.Offset - params.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link("prev", map[string]string{"offset": strconv.Itoa(prev)}))
+	}
+	if page.NextCursor != "" {
+		if params.Cursor == nil {
+			links = append(links, link("next", map[string]string{"offset": strconv.Itoa(params.Offset + params.Limit)}))
+		} else {
+			links = append(links, link("next", map[string]string{"cursor": page.NextCursor}))
+		}
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestParseListParams(t *testing.T) {
+	cursor := models.NewCursor([]models.SortField{{Field: "price"}}, models.Course{Id: "x", Price: 5}).Encode()
+	tests := []struct {
+		query string
+		field string
+		check func(p models.ListCoursesParams) bool
+	}{
+		{"", "", func(p models.ListCoursesParams) bool {
+			return p.Limit == models.DefaultPageLimit && models.FormatSort(p.Sort) == "name" && p.Cursor == nil
+		}},
+		{"limit=5&offset=10&technology=Go&name=Le", "", func(p models.ListCoursesParams) bool {
+			return p.Limit == 5 && p.Offset == 10 && p.Technology == "Go" && p.NamePrefix == "Le"
+		}},
+		{"min_price=1.5&max_price=10", "", func(p models.ListCoursesParams) bool {
+			return *p.MinPrice == 1.5 && *p.MaxPrice == 10
+		}},
+		{"sort=-price,name", "", func(p models.ListCoursesParams) bool { return models.FormatSort(p.Sort) == "-price,name" }},
+		{"sort=price&cursor=" + cursor, "", func(p models.ListCoursesParams) bool { return p.Cursor != nil && p.Cursor.Price == 5 }},
+		{"limit=0", "limit", nil},
+		{"limit=101", "limit", nil},
+		{"limit=ten", "limit", nil},
+		{"offset=-1", "offset", nil},
+		{"min_price=cheap", "min_price", nil},
+		{"min_price=10&max_price=5", "min_price", nil},
+		{"sort=technology", "sort", nil},
+		{"cursor=garbage", "cursor", nil},
+		{"offset=0&sort=price&cursor=" + cursor, "cursor", nil},
+		// the cursor was made for another sort
+		{"cursor=" + cursor, "cursor", nil},
+		{"include_deleted=maybe", "include_deleted", nil},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		params, err := parseListParams(query)
+		if tt.field == "" {
+			if err != nil || !tt.check(params) {
+				t.Errorf("%q: got %+v, %v", tt.query, params, err)
+			}
+			continue
+		}
+		var validationErr models.ValidationError
+		if !errors.As(err, &validationErr) || validationErr[0].Field != tt.field {
+			t.Errorf("%q: got %v, want an error for %s", tt.query, err, tt.field)
+		}
+	}
+}
+
+// following the next links visits every course once, by offset and by cursor
+func TestShowCoursesLinks(t *testing.T) {
+	s := authServer(t, false)
+	seedCourses(t, s, 7)
+	for _, byCursor := range []bool{false, true} {
+		next := "/courses?limit=3&sort=-price,name"
+		seen := map[string]bool{}
+		for pages := 0; next != ""; pages++ {
+			if pages > 5 {
+				t.Fatalf("cursor %v: more pages than courses", byCursor)
+			}
+			w := httptest.NewRecorder()
+			s.showCourses(w, httptest.NewRequest(http.MethodGet, next, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status %d: %s", next, w.Code, w.Body)
+			}
+			var list models.CourseList
+			if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+			if list.Pagination.Total != 7 {
+				t.Errorf("%s: total %d, want 7", next, list.Pagination.Total)
+			}
+			for _, course := range list.Data {
+				if seen[course.Id] {
+					t.Errorf("%s: %s shown twice", next, course.Name)
+				}
+				seen[course.Id] = true
+			}
+			next = ""
+			if byCursor && pages == 0 && list.Pagination.NextCursor != "" {
+				// the first page links by offset, switch to the cursor
+				next = "/courses?limit=3&sort=-price,name&cursor=" + list.Pagination.NextCursor
+				continue
+			}
+			for _, link := range strings.Split(w.Header().Get("Link"), ", ") {
+				if target, ok := strings.CutSuffix(link, `>; rel="next"`); ok {
+					u, err := url.Parse(strings.TrimPrefix(target, "<"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					next = u.RequestURI()
+				}
+			}
+			if byCursor && next != "" && !strings.Contains(next, "cursor=") {
+				t.Errorf("%s: next link %s leaves the cursor", w.Header().Get("Link"), next)
+			}
+		}
+		if len(seen) != 7 {
+			t.Errorf("cursor %v: saw %d courses, want 7", byCursor, len(seen))
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/ratelimit"
+)
+
+// route groups with their own buckets
+const (
+	readGroup  = "read"
+	writeGroup = "write"
+)
+
+func newLimiters(cfg config.RateLimitConfig) map[string]*ratelimit.Limiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return map[string]*ratelimit.Limiter{
+		readGroup:  ratelimit.New(ratelimit.Limit{Rate: float64(cfg.ReadsPerMinute) / 60, Burst: cfg.ReadBurst}, cfg.IdleTimeout),
+		writeGroup: ratelimit.New(ratelimit.Limit{Rate: float64(cfg.WritesPerMinute) / 60, Burst: cfg.WriteBurst}, cfg.IdleTimeout),
+	}
+}
+
+// unlimited are the health probes, an orchestrator polling them must not be
+// throttled
+func unlimited(path string) bool {
+	return path == "/healthz" || path == "/readyz"
+}
+
+func routeGroup(r *http.Request) string {
+	if unlimited(r.URL.Path) {
+		return ""
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return readGroup
+	default:
+		return writeGroup
+	}
+}
+
+// rateLimitMiddleware takes a token from the client's bucket for the route
+// group and sets the RateLimit-* headers. An empty bucket gets a 429 with
+// Retry-After.
+func (s *ApiServer) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, r := s.rateLimitKey(r)
+		if s.allow(w, r, client) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allow takes a token from client's bucket for the route group of r. When the
+// bucket is empty it writes the 429 and returns false.
+func (s *ApiServer) allow(w http.ResponseWriter, r *http.Request, client string) bool {
+	limiter, ok := s.limiters[routeGroup(r)]
+	if !ok {
+		return true
+	}
+	return limitResult(w, r, limiter.Allow(client))
+}
+
+// exhausted writes the 429 and returns true when client's bucket for the
+// route group of r is empty, without taking a token
+func (s *ApiServer) exhausted(w http.ResponseWriter, r *http.Request, client string) bool {
+	limiter, ok := s.limiters[routeGroup(r)]
+	if !ok {
+		return false
+	}
+	result := limiter.Peek(client)
+	if result.Allowed {
+		return false
+	}
+	return !limitResult(w, r, result)
+}
+
+// limitResult sets the RateLimit-* headers from result, and writes the 429
+// and returns false when it was not allowed
+func limitResult(w http.ResponseWriter, r *http.Request, result ratelimit.Result) bool {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", seconds(result.RetryAfter))
+		writeProblem(w, r, http.StatusTooManyRequests, "rate limit exceeded, retry in "+seconds(result.RetryAfter)+"s")
+		return false
+	}
+	return true
+}
+
+// rateLimitKey identifies the caller: the api key resolved by
+// apiKeyMiddleware, else the subject of a valid bearer token, else the client
+// ip. A verified token is kept on the returned request so requireRole does
+// not check it twice; an invalid one is left for requireRole to reject.
+func (s *ApiServer) rateLimitKey(r *http.Request) (string, *http.Request) {
+	if claims, ok := auth.FromContext(r.Context()); ok {
+		return claims.Subject, r
+	}
+	if s.Config.Auth.Enabled && bearerToken(r) != "" {
+		if claims, err := s.verifyBearer(r); err == nil && claims.Subject != "" {
+			return "jwt:" + claims.Subject, r.WithContext(auth.NewContext(r.Context(), claims))
+		}
+	}
+	return "ip:" + s.clientIP(r), r
+}
+
+func (s *ApiServer) clientIP(r *http.Request) string {
+	if s.Config.RateLimit.TrustForwardedFor {
+		// the proxy appends the address it saw, earlier entries come from the
+		// client and can be made up
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(forwarded[strings.LastIndex(forwarded, ",")+1:])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds rounds up so clients never retry too early
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/gorilla/mux"
+)
+
+// limitedServer allows 2 reads and 1 write per client, refilling far slower
+// than a test runs
+func limitedServer(t *testing.T) (*ApiServer, http.Handler) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Auth.HMACSecret = testSecret
+	cfg.RateLimit = config.RateLimitConfig{Enabled: true, ReadsPerMinute: 1, ReadBurst: 2, WritesPerMinute: 1, WriteBurst: 1}
+	s := NewApiServer(cfg, mux.NewRouter(), database.NewMemoryStore())
+	s.Auth = authServer(t, true).Auth
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	return s, s.apiKeyMiddleware(s.rateLimitMiddleware(ok))
+}
+
+func limitedRequest(method, ip string, header ...string) *http.Request {
+	r := httptest.NewRequest(method, "/courses", nil)
+	r.RemoteAddr = ip + ":1234"
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return r
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	_, handler := limitedServer(t)
+	steps := []struct {
+		status    int
+		remaining string
+	}{
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	}
+	for i, step := range steps {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, limitedRequest(http.MethodGet, "10.0.0.1"))
+		if w.Code != step.status {
+			t.Errorf("read %d: status %d, want %d", i, w.Code, step.status)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("read %d: RateLimit-Limit %q, want 2", i, got)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != step.remaining {
+			t.Errorf("read %d: RateLimit-Remaining %q, want %s", i, got, step.remaining)
+		}
+		if w.Header().Get("RateLimit-Reset") == "" {
+			t.Errorf("read %d: no RateLimit-Reset", i)
+		}
+		retryAfter := w.Header().Get("Retry-After")
+		if (step.status == http.StatusTooManyRequests) != (retryAfter != "") {
+			t.Errorf("read %d: Retry-After %q with status %d", i, retryAfter, w.Code)
+		}
+		if step.status == http.StatusTooManyRequests && retryAfter != "60" {
+			t.Errorf("read %d: Retry-After %q, want 60 at one read a minute", i, retryAfter)
+		}
+	}
+}
+
+func TestRateLimitBuckets(t *testing.T) {
+	_, handler := limitedServer(t)
+	status := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	if status(limitedRequest(http.MethodPost, "10.0.0.1")) != http.StatusOK {
+		t.Fatal("first write was limited")
+	}
+	if got := status(limitedRequest(http.MethodDelete, "10.0.0.1")); got != http.StatusTooManyRequests {
+		t.Errorf("second write: status %d, want 429", got)
+	}
+	if got := status(limitedRequest(http.MethodGet, "10.0.0.1")); got != http.StatusOK {
+		t.Errorf("read after the writes ran out: status %d, reads have their own bucket", got)
+	}
+	if got := status(limitedRequest(http.MethodPost, "10.0.0.2")); got != http.StatusOK {
+		t.Errorf("write from another ip: status %d, want 200", got)
+	}
+	// a valid token gets a bucket of its own on a shared ip
+	token := bearer(t, testSecret, time.Hour, auth.RoleEditor)
+	if got := status(limitedRequest(http.MethodPost, "10.0.0.1", "Authorization", token)); got != http.StatusOK {
+		t.Errorf("write with a token: status %d, want 200", got)
+	}
+	health := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	for i := 0; i < 5; i++ {
+		if got := status(health); got != http.StatusOK {
+			t.Fatalf("health probe %d: status %d, probes are not limited", i, got)
+		}
+	}
+}
+
+// every key lookup takes a token from the caller's ip bucket first, guessing
+// keys runs into a 429 instead of a query per guess
+func TestBadAPIKeysAreRateLimited(t *testing.T) {
+	s, handler := limitedServer(t)
+	_, valid := addAPIKey(t, s, nil, auth.RoleEditor)
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, limitedRequest(http.MethodGet, "10.0.0.1", apiKeyHeader, "capi_guess"))
+		if w.Code != want {
+			t.Errorf("guess %d: status %d, want %d", i, w.Code, want)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, limitedRequest(http.MethodGet, "10.0.0.2", apiKeyHeader, valid))
+	if w.Code != http.StatusOK {
+		t.Errorf("valid key from another ip: status %d, want 200", w.Code)
+	}
+}
+
+// valid keys only count against their own bucket, several keys behind one ip
+// do not share its budget
+func TestAPIKeysHaveTheirOwnBuckets(t *testing.T) {
+	s, handler := limitedServer(t)
+	_, first := addAPIKey(t, s, nil, auth.RoleReader)
+	_, second := addAPIKey(t, s, nil, auth.RoleReader)
+	for _, key := range []string{first, second} {
+		for i, remaining := range []string{"1", "0"} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, limitedRequest(http.MethodGet, "10.0.0.1", apiKeyHeader, key))
+			if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != remaining {
+				t.Errorf("read %d: status %d, remaining %s, want 200 and %s", i, w.Code, w.Header().Get("RateLimit-Remaining"), remaining)
+			}
+		}
+	}
+	// the ip bucket is untouched
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, limitedRequest(http.MethodGet, "10.0.0.1"))
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("anonymous read: status %d, remaining %s, want 200 and 1", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+}
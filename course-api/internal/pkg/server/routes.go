@@ -2,11 +2,15 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
-	"log"
+	"mime"
 	"net/http"
+	"time"
 
+	"github.com/course-api/internal/pkg/codec"
 	"github.com/course-api/internal/pkg/models"
+	"github.com/course-api/internal/pkg/search"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
@@ -20,163 +24,249 @@ func (s *ApiServer) Homelander(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *ApiServer) showCourses(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	courses, err := s.Db.GetAll(r.Context())
+	params, err := parseListParams(r.URL.Query())
 	if err != nil {
-		log.Println("err in fetching courses:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode("oops something went wrong")
+		writeQueryError(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(courses)
+	if params.IncludeDeleted && !s.canSeeDeleted(w, r) {
+		return
+	}
+	page, err := s.Db.GetAll(r.Context(), params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setLinkHeader(w, r, params, page)
+	writeResponse(w, r, http.StatusOK, models.CourseList{
+		Data: page.Courses,
+		Pagination: models.Pagination{
+			Limit:      params.Limit,
+			Offset:     params.Offset,
+			Total:      page.Total,
+			NextCursor: page.NextCursor,
+		},
+	})
 }
 
 func (s *ApiServer) createCourse(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	var receivedCourse models.CreateCourseParams
-	// reading the body io
-	err := json.NewDecoder(r.Body).Decode(&receivedCourse)
-	if err == io.EOF {
-		log.Println("Payload not provided:", err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode("please provide payload")
+	if !decodeBody(w, r, &receivedCourse) {
 		return
 	}
-
-	if err != nil {
-		log.Println("Could not parse request Body:", err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode("problem with payload")
+	if err := receivedCourse.Validate(); err != nil {
+		writeError(w, r, err)
 		return
 	}
-
-	if receivedCourse.IsEmpty() {
-		log.Println("Empty payload")
-		w.WriteHeader(http.StatusExpectationFailed)
-		json.NewEncoder(w).Encode("Payload is empty")
+	newCourse, err := s.Db.Create(r.Context(), receivedCourse)
+	if err != nil {
+		writeError(w, r, err)
 		return
-	} else {
-		newCourse, err := s.Db.Create(r.Context(), receivedCourse)
-		if err != nil {
-			log.Printf("DB error when creating", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode("oops something went wrong")
-			return
-		}
-
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(newCourse)
 	}
+	setETag(w, r, newCourse.Version)
+	writeResponse(w, r, http.StatusCreated, newCourse)
 }
 
 func (s *ApiServer) showCourse(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	params := mux.Vars(r)
-
-	val, ok := params["id"]
+	id, ok := parseID(w, r)
 	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode("No id provided")
-	} else {
-		// consider id provided
-		id, err := uuid.Parse(val)
-		if err != nil {
-			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode("could not read id from input")
-			return
-		}
-		course, err := s.Db.GetByID(r.Context(), id)
-		if err != nil {
-
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(err.Error())
-			return
-		}
-		json.NewEncoder(w).Encode(course)
+		return
 	}
+	includeDeleted, err := parseBool(r.URL.Query(), "include_deleted")
+	if err != nil {
+		writeQueryError(w, r, err)
+		return
+	}
+	if includeDeleted && !s.canSeeDeleted(w, r) {
+		return
+	}
+	course, err := s.Db.GetByID(r.Context(), id, includeDeleted)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, r, course.Version)
+	if notModified(r, course.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, course)
 }
 
 func (s *ApiServer) updateCourse(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	params := mux.Vars(r)
-	val, ok := params["id"]
-
-	// check if params["id"] is present or not
+	receivedId, ok := parseID(w, r)
 	if !ok {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode("no id provided")
 		return
 	}
-	receivedId, err := uuid.Parse(val)
-	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode("could not read id from input")
+	version, ok := s.ifMatch(w, r, receivedId, true)
+	if !ok {
 		return
 	}
 	// need to validate the body of the request receivd
 	var receivedCourse models.UpdateCourseParams
-	err = json.NewDecoder(r.Body).Decode(&receivedCourse)
-	if err == io.EOF {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode("no payload provided")
+	if !decodeBody(w, r, &receivedCourse) {
 		return
 	}
+	if err := receivedCourse.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	course, err := s.Db.Update(r.Context(), receivedId, receivedCourse, version)
 	if err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode("invalid payload provided")
+		writeError(w, r, err)
 		return
 	}
+	setETag(w, r, course.Version)
+	writeResponse(w, r, http.StatusOK, course)
+}
 
-	if receivedCourse.IsEmpty() {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode("payload contain empty values")
+// patchCourse accepts JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902)
+// documents. Plain application/json bodies are treated as merge patches.
+// If-Match is optional here, the patch applies to whatever is stored.
+func (s *ApiServer) patchCourse(w http.ResponseWriter, r *http.Request) {
+	receivedId, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	version, ok := s.ifMatch(w, r, receivedId, false)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "no payload provided")
 		return
 	}
 
-	course, err := s.Db.Update(r.Context(), receivedId, receivedCourse)
+	var patch models.CoursePatch
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json", "application/json":
+		if !json.Valid(body) {
+			writeProblem(w, r, http.StatusBadRequest, "invalid merge patch document")
+			return
+		}
+		patch = models.MergePatch(body)
+	case "application/json-patch+json":
+		jsonPatch, err := models.DecodeJSONPatch(body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "invalid json patch document")
+			return
+		}
+		patch = jsonPatch
+	default:
+		w.Header().Set("Accept-Patch", "application/merge-patch+json, application/json-patch+json")
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "unsupported patch format")
+		return
+	}
 
+	course, err := s.Db.Patch(r.Context(), receivedId, patch, version)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(err.Error())
+		writeError(w, r, err)
 		return
 	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(course)
-
+	setETag(w, r, course.Version)
+	writeResponse(w, r, http.StatusOK, course)
 }
 
 func (s *ApiServer) deleteCourse(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	// check if params["id"] is present
-	params := mux.Vars(r)
-
-	val, ok := params["id"]
-
+	receivedId, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	version, ok := s.ifMatch(w, r, receivedId, true)
 	if !ok {
-		// id is not provided
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode("no id provided")
 		return
 	}
+	// the course is only marked, see restoreCourse and purgeCourse
+	if err := s.Db.Delete(r.Context(), receivedId, version, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	receivedId, err := uuid.Parse(val)
+// searchCourses ranks courses by how well their name and technologies match
+// q and marks the matching words
+func (s *ApiServer) searchCourses(w http.ResponseWriter, r *http.Request) {
+	params, err := parseSearchParams(r.URL.Query())
 	if err != nil {
-		log.Println("error in parsing received ID")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode("could not parse Id")
+		writeQueryError(w, r, err)
 		return
 	}
-	err = s.Db.Delete(r.Context(), receivedId)
+	page, err := s.Db.Search(r.Context(), params)
 	if err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode("failed to delete record")
+		writeError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
-	json.NewEncoder(w).Encode("record deleted sucessfully")
-	return
+	for i := range page.Hits {
+		page.Hits[i].Highlights = highlight(page.Hits[i].Course, params.Terms)
+	}
+	writeResponse(w, r, http.StatusOK, models.SearchResults{
+		Data: page.Hits,
+		Pagination: models.Pagination{
+			Limit:  params.Limit,
+			Offset: params.Offset,
+			Total:  page.Total,
+		},
+	})
+}
+
+// highlight returns the fields of course that contain one of terms
+func highlight(course models.Course, terms []string) *models.Highlights {
+	var h models.Highlights
+	if name, ok := search.Highlight(course.Name, terms); ok {
+		h.Name = name
+	}
+	for _, technology := range course.Technology {
+		if marked, ok := search.Highlight(technology, terms); ok {
+			h.Technology = append(h.Technology, marked)
+		}
+	}
+	if h.Name == "" && h.Technology == nil {
+		return nil
+	}
+	return &h
+}
+
+// showTechnologies lists the technologies in use with their course counts
+func (s *ApiServer) showTechnologies(w http.ResponseWriter, r *http.Request) {
+	technologies, err := s.Db.ListTechnologies(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, r, http.StatusOK, models.TechnologyList{Data: technologies})
+}
+
+// parseID reads the {id} path variable, writing a 400 when it is not a uuid
+func parseID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "id must be a valid uuid",
+			models.FieldError{Field: "id", Message: "must be a valid uuid"})
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// decodeBody reads the body into v in the format of its Content-Type, json
+// when there is none. Other formats get a 415, a missing or malformed body a
+// 400.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	c := codec.ForContentType(r.Header.Get("Content-Type"))
+	if c == nil {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "unsupported content type, send one of "+codec.MediaTypes())
+		return false
+	}
+	err := c.Decode(r.Body, v)
+	if errors.Is(err, io.EOF) {
+		writeProblem(w, r, http.StatusBadRequest, "please provide payload")
+		return false
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "could not parse request body: "+err.Error())
+		return false
+	}
+	return true
 }
@@ -2,60 +2,156 @@ package server
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/config"
 	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/metrics"
+	"github.com/course-api/internal/pkg/ratelimit"
+	"github.com/course-api/internal/pkg/tracing"
 	"github.com/gorilla/mux"
 )
 
 type ApiServer struct {
 	Addr    string
 	Handler *mux.Router
-	Db      *database.CoursesDBSession
+	Db      database.Interface
+	Config  config.Config
+	// checks bearer tokens when Config.Auth is enabled, nil when no jwt keys
+	// are configured and only api keys are accepted
+	Auth *auth.Verifier
+	// token buckets per route group, nil when rate limiting is off
+	limiters map[string]*ratelimit.Limiter
+	// prometheus collectors, nil when metrics are off
+	metrics *metrics.Metrics
+	// OpenAPI document, built once in SetUpRoutes
+	spec map[string]any
+	// runs once the database answers, before the server reports ready. Used
+	// to apply migrations on start.
+	Startup func(ctx context.Context) error
+	health  health
 }
 
-func NewApiServer(addr string, handler *mux.Router, db *database.CoursesDBSession) *ApiServer {
-	return &ApiServer{
-		Addr:    addr,
-		Handler: handler,
-		Db:      db,
+// NewApiServer sets up the server around db. With metrics or tracing enabled
+// db is wrapped so every call is measured and traced. Metrics wrap the store
+// itself so they can register its pool stats.
+func NewApiServer(cfg config.Config, handler *mux.Router, db database.Interface) *ApiServer {
+	s := &ApiServer{
+		Addr:     cfg.Server.Addr,
+		Handler:  handler,
+		Db:       db,
+		Config:   cfg,
+		limiters: newLimiters(cfg.RateLimit),
 	}
+	if cfg.Metrics.Enabled {
+		s.metrics = metrics.New()
+		s.Db = s.metrics.InstrumentDB(s.Db)
+	}
+	if cfg.Tracing.Exporter != tracing.None {
+		s.Db = tracing.InstrumentDB(s.Db)
+	}
+	return s
 }
 
+// Run serves until ctx is cancelled or the process gets SIGINT/SIGTERM. The
+// database is connected in the background, /readyz reports not ready until it
+// answers. On shutdown it stops accepting connections, waits for in-flight
+// requests up to Config.Server.ShutdownTimeout and closes the database. A
+// clean shutdown returns nil.
 func (s *ApiServer) Run(ctx context.Context) error {
-	log.Println("Server starting on:", s.Addr)
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("server starting", "addr", s.Addr)
 	server := &http.Server{
-		Addr:    s.Addr,
-		Handler: s.Handler,
+		Addr:              s.Addr,
+		Handler:           loggingMiddleware(s.Handler),
+		ReadHeaderTimeout: s.Config.Server.ReadHeaderTimeout,
+		ReadTimeout:       s.Config.Server.ReadTimeout,
+		WriteTimeout:      s.Config.Server.WriteTimeout,
+		IdleTimeout:       s.Config.Server.IdleTimeout,
 	}
 
 	s.SetUpRoutes()
-	s.Handler.Use(loggingMiddleware)
-	log.Println("router set")
-	err := s.Db.Ping(ctx)
-	if err != nil {
-		panic("DB server not connected")
+	s.Handler.Use(s.tracingMiddleware, s.metricsMiddleware, s.apiKeyMiddleware, s.rateLimitMiddleware, s.negotiationMiddleware)
+	connectCtx, cancelConnect := context.WithCancel(ctx)
+	connected := make(chan struct{})
+	go func() {
+		defer close(connected)
+		s.connect(connectCtx)
+	}()
+	// the retry loop has to stop before the database is closed
+	closeDb := func() error {
+		cancelConnect()
+		<-connected
+		return s.Db.Close()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serveErr:
+		// the listener failed before any shutdown was asked for
+		closeDb()
+		return err
+	case <-ctx.Done():
 	}
-	return server.ListenAndServe()
-}
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		startTime := time.Now()
-		log.Printf("Request is %s ,%s", r.URL, r.URL.Path)
-		next.ServeHTTP(w, r)
-		duration := time.Since(startTime)
-		log.Printf("Response: %s %s - %v", r.Method, r.URL.Path, duration)
-	})
+	s.health.draining.Store(true)
+	slog.Info("shutting down, draining in-flight requests", "timeout", s.Config.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.Config.Server.ShutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		// deadline passed, cut the remaining connections
+		server.Close()
+		err = fmt.Errorf("shutdown: %w", err)
+	}
+	<-serveErr
+	return errors.Join(err, closeDb())
 }
 
 func (s *ApiServer) SetUpRoutes() {
+	s.Handler.NotFoundHandler = s.metricsMiddleware(http.HandlerFunc(notFoundHandler))
+	s.Handler.MethodNotAllowedHandler = s.metricsMiddleware(http.HandlerFunc(methodNotAllowedHandler))
 	s.Handler.HandleFunc("/", s.Homelander).Methods("GET")
-	s.Handler.HandleFunc("/courses", s.showCourses).Methods("GET")
-	s.Handler.HandleFunc("/course", s.createCourse).Methods("POST")
-	s.Handler.HandleFunc("/courses/{id}", s.showCourse).Methods("GET")
-	s.Handler.HandleFunc("/courses/{id}", s.updateCourse).Methods("PUT")
-	s.Handler.HandleFunc("/courses/{id}", s.deleteCourse).Methods("DELETE")
+	s.Handler.Handle("/courses", s.requireRole(auth.RoleReader, s.showCourses)).Methods("GET")
+	s.Handler.Handle("/course", s.requireRole(auth.RoleEditor, s.createCourse)).Methods("POST")
+	s.Handler.Handle("/courses/bulk", s.requireRole(auth.RoleEditor, s.createCourses)).Methods("POST")
+	// before /courses/{id}, which would take "search" for an id
+	s.Handler.Handle("/courses/export", s.requireRole(auth.RoleReader, s.exportCourses)).Methods("GET")
+	s.Handler.Handle("/courses/import", s.requireRole(auth.RoleEditor, s.importCourses)).Methods("POST")
+	s.Handler.Handle("/courses/search", s.requireRole(auth.RoleReader, s.searchCourses)).Methods("GET")
+	s.Handler.Handle("/courses/{id}", s.requireRole(auth.RoleReader, s.showCourse)).Methods("GET")
+	s.Handler.Handle("/courses/{id}", s.requireRole(auth.RoleEditor, s.updateCourse)).Methods("PUT")
+	if s.Config.Features.Patch {
+		s.Handler.Handle("/courses/{id}", s.requireRole(auth.RoleEditor, s.patchCourse)).Methods("PATCH")
+	}
+	s.Handler.Handle("/courses/{id}", s.requireRole(auth.RoleEditor, s.deleteCourse)).Methods("DELETE")
+	s.Handler.Handle("/courses/{id}/restore", s.requireRole(auth.RoleEditor, s.restoreCourse)).Methods("POST")
+	s.Handler.Handle("/technologies", s.requireRole(auth.RoleReader, s.showTechnologies)).Methods("GET")
+	if adminRoutes(s.Config) {
+		s.Handler.Handle("/admin/api-keys", s.requireRole(auth.RoleAdmin, s.listAPIKeys)).Methods("GET")
+		s.Handler.Handle("/admin/api-keys", s.requireRole(auth.RoleAdmin, s.createAPIKey)).Methods("POST")
+		s.Handler.Handle("/admin/api-keys/{id}/rotate", s.requireRole(auth.RoleAdmin, s.rotateAPIKey)).Methods("POST")
+		s.Handler.Handle("/admin/api-keys/{id}", s.requireRole(auth.RoleAdmin, s.revokeAPIKey)).Methods("DELETE")
+		s.Handler.Handle("/admin/courses/{id}", s.requireRole(auth.RoleAdmin, s.purgeCourse)).Methods("DELETE")
+	}
+	if s.metrics != nil {
+		s.Handler.Handle("/metrics", s.metrics.Handler()).Methods("GET")
+	}
+	s.Handler.HandleFunc("/healthz", s.healthz).Methods("GET")
+	s.Handler.HandleFunc("/readyz", s.readyz).Methods("GET")
+	s.Handler.HandleFunc("/openapi.json", s.showOpenAPI).Methods("GET")
+	s.Handler.HandleFunc("/docs", s.showDocs).Methods("GET")
+	s.spec = s.openAPISpec()
 }
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/config"
+	"github.com/course-api/internal/pkg/database"
+	"github.com/gorilla/mux"
+)
+
+// closingStore records whether Run closed the database
+type closingStore struct {
+	database.Interface
+	closed atomic.Bool
+}
+
+func (s *closingStore) Close() error {
+	s.closed.Store(true)
+	return s.Interface.Close()
+}
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// runServer starts Run in the background and waits until it serves. Its logs
+// go to a buffer of this test, so a request that outlives the test cannot
+// write into the logs of the next one.
+func runServer(t *testing.T, ctx context.Context, cfg config.Config, db database.Interface) (*ApiServer, chan error) {
+	t.Helper()
+	captureLogs(t)
+	cfg.Server.Addr = freeAddr(t)
+	cfg.Auth.Enabled = false
+	s := NewApiServer(cfg, mux.NewRouter(), db)
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get("http://" + s.Addr + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			return s, done
+		}
+	}
+	t.Fatal("server did not start")
+	return nil, nil
+}
+
+func TestRunDrainsRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := &closingStore{Interface: slowStore{Interface: database.NewMemoryStore(), delay: 300 * time.Millisecond}}
+	s, done := runServer(t, ctx, config.Default(), db)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + s.Addr + "/courses")
+		if err != nil {
+			t.Error(err)
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	// let the request reach the handler before shutting down
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("in-flight request got %d, want 200", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v after a clean shutdown", err)
+	}
+	if !db.closed.Load() {
+		t.Error("database not closed on shutdown")
+	}
+	if _, err := http.Get("http://" + s.Addr + "/healthz"); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
+
+func TestRunShutdownDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := config.Default()
+	cfg.Server.ShutdownTimeout = 50 * time.Millisecond
+	db := &closingStore{Interface: slowStore{Interface: database.NewMemoryStore(), delay: time.Second}}
+	s, done := runServer(t, ctx, cfg, db)
+
+	requested := make(chan struct{})
+	go func() {
+		defer close(requested)
+		if resp, err := http.Get("http://" + s.Addr + "/courses"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	defer func() { <-requested }()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run returned %v, want the shutdown deadline", err)
+	}
+	if !db.closed.Load() {
+		t.Error("database not closed after the shutdown deadline")
+	}
+}
+
+func TestRunListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	cfg := config.Default()
+	cfg.Server.Addr = l.Addr().String()
+	db := &closingStore{Interface: database.NewMemoryStore()}
+	s := NewApiServer(cfg, mux.NewRouter(), db)
+	if err := s.Run(context.Background()); err == nil {
+		t.Error("Run on an address in use returned nil")
+	}
+	if !db.closed.Load() {
+		t.Error("database not closed after the listener failed")
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/course-api/internal/pkg/auth"
+)
+
+// canSeeDeleted writes a 403 unless the caller is an admin, only they get to
+// list and read deleted courses. Like the admin routes this is off with auth
+// disabled.
+func (s *ApiServer) canSeeDeleted(w http.ResponseWriter, r *http.Request) bool {
+	if !adminRoutes(s.Config) {
+		writeProblem(w, r, http.StatusForbidden, "include_deleted needs auth enabled and the "+auth.RoleAdmin+" role")
+		return false
+	}
+	if claims, ok := auth.FromContext(r.Context()); ok && claims.HasRole(auth.RoleAdmin) {
+		return true
+	}
+	writeProblem(w, r, http.StatusForbidden, "include_deleted needs the "+auth.RoleAdmin+" role")
+	return false
+}
+
+// restoreCourse brings back a deleted course. If-Match is optional, the ETag
+// of a deleted course comes from GET /courses/{id}?include_deleted=true.
+func (s *ApiServer) restoreCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	version, ok := s.ifMatch(w, r, id, false)
+	if !ok {
+		return
+	}
+	course, err := s.Db.Restore(r.Context(), id, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	setETag(w, r, course.Version)
+	writeResponse(w, r, http.StatusOK, course)
+}
+
+// purgeCourse removes a deleted course for good. Courses have to be deleted
+// first, so a single mistaken call cannot lose one.
+func (s *ApiServer) purgeCourse(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.Db.Purge(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
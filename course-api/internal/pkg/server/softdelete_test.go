@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+)
+
+// deleted courses are only shown to admins, and to nobody with auth disabled
+func TestIncludeDeletedNeedsAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		auth          bool
+		authorization string
+		status        int
+	}{
+		{"auth disabled", false, "", http.StatusForbidden},
+		{"reader", true, bearer(t, testSecret, time.Hour, auth.RoleReader), http.StatusForbidden},
+		{"editor", true, bearer(t, testSecret, time.Hour, auth.RoleEditor), http.StatusForbidden},
+		{"admin", true, bearer(t, testSecret, time.Hour, auth.RoleAdmin), http.StatusOK},
+	}
+	for _, tt := range tests {
+		s := authServer(t, tt.auth)
+		s.SetUpRoutes()
+		ctx := context.Background()
+		course, err := s.Db.Create(ctx, models.CreateCourseParams{Name: "Go", Price: 10, Technology: []string{"Go"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Db.Delete(ctx, uuid.MustParse(course.Id), 0, time.Now().UTC()); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{"/courses?include_deleted=true", "/courses/" + course.Id + "?include_deleted=true"} {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("%s: GET %s: status %d, want %d", tt.name, path, w.Code, tt.status)
+			}
+		}
+	}
+}
+
+func TestRestoreCourse(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	ctx := context.Background()
+	course, err := s.Db.Create(ctx, models.CreateCourseParams{Name: "Go", Price: 10, Technology: []string{"Go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restore := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/courses/"+course.Id+"/restore", nil))
+		return w
+	}
+	if w := restore(); w.Code != http.StatusConflict {
+		t.Errorf("restore a live course: status %d, want 409", w.Code)
+	}
+	if err := s.Db.Delete(ctx, uuid.MustParse(course.Id), 0, time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	w := restore()
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3-json"` {
+		t.Errorf("restore: status %d, ETag %s, want 200 and version 3", w.Code, w.Header().Get("ETag"))
+	}
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/courses/"+course.Id, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("get the restored course: status %d", w.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/course-api/internal/pkg/models"
+)
+
+func TestShowTechnologies(t *testing.T) {
+	s := authServer(t, false)
+	s.SetUpRoutes()
+	for _, params := range []models.CreateCourseParams{
+		{Name: "Go", Price: 10, Technology: []string{"Go", "gRPC"}},
+		{Name: "Rust", Price: 20, Technology: []string{"Rust", "gRPC"}},
+	} {
+		if _, err := s.Db.Create(context.Background(), params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/technologies", nil))
+	var list models.TechnologyList
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil {
+		t.Fatalf("/technologies: %d %s", w.Code, w.Body)
+	}
+	want := []models.Technology{{Name: "Go", CourseCount: 1}, {Name: "Rust", CourseCount: 1}, {Name: "gRPC", CourseCount: 2}}
+	if !slices.Equal(list.Data, want) {
+		t.Errorf("got %v, want %v", list.Data, want)
+	}
+}
+
+func TestShowTechnologiesNeedsReader(t *testing.T) {
+	s := authServer(t, true)
+	s.SetUpRoutes()
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/technologies", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("/technologies without credentials: %d, want 401", w.Code)
+	}
+}
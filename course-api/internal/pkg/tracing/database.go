@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/course-api/internal/pkg/tracing"
+
+// tracedDB puts a span around every repository call. The statements it runs
+// show up as child spans from the otelsql driver wrapper.
+type tracedDB struct {
+	db      database.Interface
+	backend string
+	tracer  trace.Tracer
+}
+
+func InstrumentDB(db database.Interface) database.Interface {
+	return &tracedDB{
+		db:      db,
+		backend: database.BackendName(db),
+		tracer:  otel.Tracer(instrumentationName),
+	}
+}
+
+func (t *tracedDB) Unwrap() database.Interface {
+	return t.db
+}
+
+func (t *tracedDB) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemKey.String(t.backend), semconv.DBOperationName(op))
+	return t.tracer.Start(ctx, "db "+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func courseID(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("course.id", id.String())
+}
+
+// expectedVersion is the If-Match version of a write, 0 when unconditional
+func expectedVersion(version int64) attribute.KeyValue {
+	return attribute.Int64("course.expected_version", version)
+}
+
+func (t *tracedDB) Ping(ctx context.Context) (err error) {
+	ctx, span := t.start(ctx, "Ping")
+	defer func() { end(span, err) }()
+	return t.db.Ping(ctx)
+}
+
+func (t *tracedDB) Close() error {
+	return t.db.Close()
+}
+
+func (t *tracedDB) GetAll(ctx context.Context, params models.ListCoursesParams) (page models.CoursePage, err error) {
+	ctx, span := t.start(ctx, "GetAll", attribute.Int("page.limit", params.Limit), attribute.Int("page.offset", params.Offset))
+	defer func() { end(span, err) }()
+	page, err = t.db.GetAll(ctx, params)
+	span.SetAttributes(attribute.Int("page.rows", len(page.Courses)))
+	return page, err
+}
+
+func (t *tracedDB) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (course models.Course, err error) {
+	ctx, span := t.start(ctx, "GetByID", courseID(id), attribute.Bool("course.include_deleted", includeDeleted))
+	defer func() { end(span, err) }()
+	return t.db.GetByID(ctx, id, includeDeleted)
+}
+
+func (t *tracedDB) Create(ctx context.Context, params models.CreateCourseParams) (course models.Course, err error) {
+	ctx, span := t.start(ctx, "Create")
+	defer func() { end(span, err) }()
+	return t.db.Create(ctx, params)
+}
+
+func (t *tracedDB) CreateMany(ctx context.Context, params []models.CreateCourseParams, atomic bool) (results []database.BulkResult, err error) {
+	ctx, span := t.start(ctx, "CreateMany", attribute.Int("bulk.courses", len(params)), attribute.Bool("bulk.atomic", atomic))
+	defer func() { end(span, err) }()
+	return t.db.CreateMany(ctx, params, atomic)
+}
+
+func (t *tracedDB) Import(ctx context.Context, courses []models.Course, dryRun bool) (summary models.ImportSummary, err error) {
+	ctx, span := t.start(ctx, "Import", attribute.Int("import.courses", len(courses)), attribute.Bool("import.dry_run", dryRun))
+	defer func() { end(span, err) }()
+	return t.db.Import(ctx, courses, dryRun)
+}
+
+func (t *tracedDB) Update(ctx context.Context, id uuid.UUID, params models.UpdateCourseParams, version int64) (course models.Course, err error) {
+	ctx, span := t.start(ctx, "Update", courseID(id), expectedVersion(version))
+	defer func() { end(span, err) }()
+	return t.db.Update(ctx, id, params, version)
+}
+
+func (t *tracedDB) Patch(ctx context.Context, id uuid.UUID, patch models.CoursePatch, version int64) (course models.Course, err error) {
+	ctx, span := t.start(ctx, "Patch", courseID(id), expectedVersion(version))
+	defer func() { end(span, err) }()
+	return t.db.Patch(ctx, id, patch, version)
+}
+
+func (t *tracedDB) Delete(ctx context.Context, id uuid.UUID, version int64, at time.Time) (err error) {
+	ctx, span := t.start(ctx, "Delete", courseID(id), expectedVersion(version))
+	defer func() { end(span, err) }()
+	return t.db.Delete(ctx, id, version, at)
+}
+
+func (t *tracedDB) Restore(ctx context.Context, id uuid.UUID, version int64) (course models.Course, err error) {
+	ctx, span := t.start(ctx, "Restore", courseID(id), expectedVersion(version))
+	defer func() { end(span, err) }()
+	return t.db.Restore(ctx, id, version)
+}
+
+func (t *tracedDB) Purge(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := t.start(ctx, "Purge", courseID(id))
+	defer func() { end(span, err) }()
+	return t.db.Purge(ctx, id)
+}
+
+func (t *tracedDB) Search(ctx context.Context, params models.SearchParams) (page models.SearchPage, err error) {
+	ctx, span := t.start(ctx, "Search", attribute.Int("search.terms", len(params.Terms)),
+		attribute.Int("page.limit", params.Limit), attribute.Int("page.offset", params.Offset))
+	defer func() { end(span, err) }()
+	page, err = t.db.Search(ctx, params)
+	span.SetAttributes(attribute.Int("search.hits", page.Total))
+	return page, err
+}
+
+func (t *tracedDB) ListTechnologies(ctx context.Context) (technologies []models.Technology, err error) {
+	ctx, span := t.start(ctx, "ListTechnologies")
+	defer func() { end(span, err) }()
+	technologies, err = t.db.ListTechnologies(ctx)
+	span.SetAttributes(attribute.Int("technologies.rows", len(technologies)))
+	return technologies, err
+}
+
+func (t *tracedDB) CreateAPIKey(ctx context.Context, key models.APIKey, hash string) (err error) {
+	ctx, span := t.start(ctx, "CreateAPIKey", attribute.String("api_key.id", key.Id))
+	defer func() { end(span, err) }()
+	return t.db.CreateAPIKey(ctx, key, hash)
+}
+
+func (t *tracedDB) ListAPIKeys(ctx context.Context) (keys []models.APIKey, err error) {
+	ctx, span := t.start(ctx, "ListAPIKeys")
+	defer func() { end(span, err) }()
+	return t.db.ListAPIKeys(ctx)
+}
+
+func (t *tracedDB) GetAPIKeyByHash(ctx context.Context, hash string) (key models.APIKey, err error) {
+	ctx, span := t.start(ctx, "GetAPIKeyByHash")
+	defer func() { end(span, err) }()
+	return t.db.GetAPIKeyByHash(ctx, hash)
+}
+
+func (t *tracedDB) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (key models.APIKey, err error) {
+	ctx, span := t.start(ctx, "RotateAPIKey", attribute.String("api_key.id", id.String()))
+	defer func() { end(span, err) }()
+	return t.db.RotateAPIKey(ctx, id, hash, prefix)
+}
+
+func (t *tracedDB) RevokeAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (key models.APIKey, err error) {
+	ctx, span := t.start(ctx, "RevokeAPIKey", attribute.String("api_key.id", id.String()))
+	defer func() { end(span, err) }()
+	return t.db.RevokeAPIKey(ctx, id, at)
+}
+
+func (t *tracedDB) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) (err error) {
+	ctx, span := t.start(ctx, "TouchAPIKey", attribute.String("api_key.id", id.String()))
+	defer func() { end(span, err) }()
+	return t.db.TouchAPIKey(ctx, id, at)
+}
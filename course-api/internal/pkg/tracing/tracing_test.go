@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func attr(span sdktrace.ReadOnlySpan, key string) string {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestInstrumentDB(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := context.Background()
+	sqlite, err := database.NewSQLiteSession(ctx, filepath.Join(t.TempDir(), "test.db"), database.PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	migrator, err := sqlite.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	db := InstrumentDB(sqlite)
+	course, err := db.Create(ctx, models.CreateCourseParams{Name: "Go", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(ctx, "request")
+	id := uuid.MustParse(course.Id)
+	if _, err := db.Update(ctx, id, models.UpdateCourseParams{Name: "Go 2", Price: 12}, 1); err != nil {
+		t.Fatal(err)
+	}
+	db.GetByID(ctx, uuid.New(), false)
+	parent.End()
+
+	var update, missing sdktrace.ReadOnlySpan
+	statements := 0
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "db Update":
+			update = span
+		case "db GetByID":
+			missing = span
+		}
+	}
+	if update == nil || missing == nil {
+		t.Fatalf("spans %v, want db Update and db GetByID", recorder.Ended())
+	}
+	// statements end before the repository call, look for them once all
+	// spans are in
+	for _, span := range recorder.Ended() {
+		if attr(span, "db.statement") != "" && span.Parent().SpanID() == update.SpanContext().SpanID() {
+			statements++
+		}
+	}
+	if update.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("db Update is not a child of the request span")
+	}
+	if attr(update, "course.id") != course.Id || attr(update, "course.expected_version") != "1" || attr(update, "db.system") != "sqlite" {
+		t.Errorf("db Update attributes %v", update.Attributes())
+	}
+	if statements == 0 {
+		t.Error("no statement spans with db.statement under db Update")
+	}
+	if missing.Status().Code != codes.Error {
+		t.Errorf("lookup of an unknown id has status %v, want an error", missing.Status())
+	}
+}
+
+// statements outside of a trace, e.g. the pool pings, create no spans
+func TestNoSpansWithoutTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx := context.Background()
+	sqlite, err := database.NewSQLiteSession(ctx, filepath.Join(t.TempDir(), "test.db"), database.PoolConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	if err := sqlite.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("%d spans recorded outside of a trace", len(spans))
+	}
+}
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, Options{Exporter: None})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(ctx); err != nil {
+		t.Error(err)
+	}
+	if _, err := Setup(ctx, Options{Exporter: "zipkin"}); err == nil {
+		t.Error("Setup accepted an unknown exporter")
+	}
+}
@@ -2,28 +2,135 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/course-api/internal/pkg/auth"
+	"github.com/course-api/internal/pkg/config"
 	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/logging"
 	"github.com/course-api/internal/pkg/server"
+	"github.com/course-api/internal/pkg/tracing"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	fmt.Println("This is going to be the server")
+	// .env is optional, in containers the environment is already set
 	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatal("Error loading .env file: ", err)
+	}
+
+	flags := flag.NewFlagSet("course-api", flag.ExitOnError)
+	printConfig := flags.Bool("print-config", false, "print the effective config (secrets redacted) and exit")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: course-api [flags]\n       course-api [flags] migrate [up|down [steps]|status]\n       course-api [flags] api-keys [list|create <name> <scopes>|revoke <id>]\n")
+		flags.PrintDefaults()
+	}
+	cfg, err := config.Load(flags, os.Args[1:])
+	if *printConfig {
+		cfg.Print(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal("Invalid config: ", err)
+	}
+	if *printConfig {
+		return
+	}
+	// the log package writes through this handler too
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format))
+
+	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		ServiceName:  cfg.Tracing.ServiceName,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("Could not set up tracing: ", err)
+	}
+	db, err := database.Open(ctx, cfg.Database.URL, database.PoolConfig{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal("Could not open database: ", err)
+	}
+
+	switch flags.Arg(0) {
+	case "migrate":
+		defer db.Close()
+		if err := runMigrate(ctx, db, flags.Args()[1:]); err != nil {
+			log.Fatal("migration failed: ", err)
+		}
+		return
+	case "api-keys":
+		defer db.Close()
+		if err := runAPIKeys(ctx, db, flags.Args()[1:]); err != nil {
+			log.Fatal("api-keys: ", err)
+		}
+		return
 	}
-	dbURL := os.Getenv("DATABASE_URL")
 	log.Println("Starting server....")
+	log.Printf("effective config:\n%s", redactedConfig(cfg))
 	router := mux.NewRouter()
-	db := database.NewCoursesDBSession(dbURL)
-	s := server.NewApiServer(":6060", router, db)
-	ctx := context.Background()
-	s.Run(ctx)
+	s := server.NewApiServer(cfg, router, db)
+	if cfg.Database.MigrateOnStart {
+		migrator, err := newMigrator(db)
+		if err != nil {
+			db.Close()
+			log.Fatal("migration failed: ", err)
+		}
+		// applied once the database answers, a failed attempt is retried
+		// with the connection
+		if migrator != nil {
+			s.Startup = func(ctx context.Context) error { return migrateUp(ctx, migrator) }
+		}
+	}
+	switch {
+	case !cfg.Auth.Enabled:
+		slog.Warn("auth is disabled, anyone can change courses")
+	case !cfg.Auth.HasJWTKeys():
+		slog.Info("no jwt keys configured, only api keys are accepted")
+	default:
+		s.Auth, err = auth.NewVerifier(auth.Options{
+			HMACSecret:    cfg.Auth.HMACSecret,
+			PublicKeyFile: cfg.Auth.PublicKeyFile,
+			JWKSFile:      cfg.Auth.JWKSFile,
+			Issuer:        cfg.Auth.Issuer,
+			Audience:      cfg.Auth.Audience,
+			Leeway:        cfg.Auth.Leeway,
+		})
+		if err != nil {
+			db.Close()
+			log.Fatal("Could not set up auth: ", err)
+		}
+	}
+	// Run owns the database from here and closes it on the way out
+	err = s.Run(ctx)
+	// flush the spans still buffered by the exporter
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Warn("could not flush traces", "err", err)
+	}
+	if err != nil {
+		log.Fatal("Server stopped: ", err)
+	}
+	log.Println("Server stopped")
+}
 
+func redactedConfig(cfg config.Config) string {
+	var b strings.Builder
+	cfg.Print(&b)
+	return b.String()
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/course-api/internal/pkg/database"
+	"github.com/course-api/internal/pkg/migrations"
+)
+
+// runMigrate handles "course-api migrate [up|down [steps]|status]"
+func runMigrate(ctx context.Context, db database.Interface, args []string) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	if migrator == nil {
+		fmt.Println("this storage backend has no schema to migrate")
+		return nil
+	}
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		return migrateUp(ctx, migrator)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		ran, err := migrator.Down(ctx, steps)
+		for _, m := range ran {
+			fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+}
+
+func migrateUp(ctx context.Context, migrator *migrations.Migrator) error {
+	ran, err := migrator.Up(ctx)
+	for _, m := range ran {
+		log.Printf("applied migration %04d_%s", m.Version, m.Name)
+	}
+	if err == nil && len(ran) == 0 {
+		log.Println("schema is up to date")
+	}
+	return err
+}
+
+// newMigrator returns nil for backends without a schema (memory)
+func newMigrator(db database.Interface) (*migrations.Migrator, error) {
+	m, ok := db.(database.Migratable)
+	if !ok {
+		return nil, nil
+	}
+	return m.Migrator()
+}